@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/spf13/cobra"
@@ -9,7 +10,7 @@ import (
 
 var (
 	addCode   string
-	addWeight int
+	addWeight string
 	addGroup  string
 )
 
@@ -17,24 +18,32 @@ var addCmd = &cobra.Command{
 	Use:   "add [word]",
 	Short: "Add or update a word in the user dictionary",
 	Long: `Adds a new word to the dictionary or updates it if it already exists.
-If the Wubi code is not provided via --code, it will be automatically generated.`,
+If the code is not provided via --code, it will be automatically generated
+for the input method selected with --scheme.`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		wordToAdd := args[0]
-
-		d := dict.NewDictionary(userDictFile)
-		if err := d.Load(); err != nil {
+		weight, err := dict.ParseWeight(addWeight)
+		if err != nil {
 			return err
 		}
 
 		finalCode := addCode
 		if finalCode == "" {
-			fmt.Println("Attempting to auto-generate Wubi code...")
-			encoder, err := dict.NewWubiEncoder(mainDictFile)
+			fmt.Printf("Attempting to auto-generate %s code...\n", scheme)
+			encoder, err := newEncoder()
 			if err != nil {
-				return fmt.Errorf("could not create wubi encoder: %w", err)
+				return fmt.Errorf("could not create %s encoder: %w", scheme, err)
 			}
 			generated, err := encoder.GenerateCode(wordToAdd)
+			var ambiguous *dict.AmbiguousCodeError
+			if errors.As(err, &ambiguous) {
+				fmt.Printf("'%s' can be encoded in more than one way:\n", wordToAdd)
+				for _, candidate := range ambiguous.Candidates {
+					fmt.Printf("  %s\n", candidate)
+				}
+				return fmt.Errorf("ambiguous code for '%s'. Please choose one with --code", wordToAdd)
+			}
 			if err != nil {
 				return fmt.Errorf("failed to generate code: %w. Please provide it manually with --code", err)
 			}
@@ -42,11 +51,13 @@ If the Wubi code is not provided via --code, it will be automatically generated.
 			fmt.Printf("Auto-generated code for '%s': %s\n", wordToAdd, finalCode)
 		}
 
-		d.AddOrUpdate(wordToAdd, finalCode, addWeight, addGroup)
-
-		fmt.Printf("Saving changes to %s...\n", userDictFile)
-		if err := d.Save(); err != nil {
-			return fmt.Errorf("failed to save dictionary: %w", err)
+		err = updateUserDict(func(d *dict.Dictionary) error {
+			d.AddOrUpdate(wordToAdd, finalCode, weight, addGroup)
+			fmt.Printf("Saving changes to %s...\n", userDictFile)
+			return nil
+		})
+		if err != nil {
+			return err
 		}
 		fmt.Println("Successfully saved.")
 
@@ -63,8 +74,8 @@ If the Wubi code is not provided via --code, it will be automatically generated.
 }
 
 func init() {
-	addCmd.Flags().StringVarP(&addCode, "code", "c", "", "Manually specify the Wubi code")
-	addCmd.Flags().IntVarP(&addWeight, "weight", "w", 100, "Specify the weight for the word")
+	addCmd.Flags().StringVarP(&addCode, "code", "c", "", "Manually specify the code")
+	addCmd.Flags().StringVarP(&addWeight, "weight", "w", "100", "Specify the weight for the word, e.g. 100 or 10%")
 	addCmd.Flags().StringVarP(&addGroup, "group", "g", "个人", "Specify the group for the word")
 	rootCmd.AddCommand(addCmd)
 }
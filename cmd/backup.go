@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/tenfyzhong/rime-dict-manager/dict"
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Manage backups of the user dictionary",
+	Long: `Every save keeps a timestamped backup of the previous user dictionary
+(see --backups). Use 'backup list' to see them and 'restore' to roll back.`,
+}
+
+var backupListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the backups of the user dictionary",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		backups, err := dict.ListBackups(userDictFile)
+		if err != nil {
+			return err
+		}
+		if len(backups) == 0 {
+			fmt.Printf("No backups found for %s\n", userDictFile)
+			return nil
+		}
+
+		fmt.Printf("Backups of %s (newest first):\n", userDictFile)
+		for _, b := range backups {
+			fmt.Printf("%-24s %s  %8d bytes\n", b.ID, b.Time.Format("2006-01-02 15:04:05"), b.Size)
+		}
+		return nil
+	},
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore [id]",
+	Short: "Restore the user dictionary from a backup",
+	Long: `Replaces the user dictionary with the backup identified by id, as shown
+by 'backup list'. A unique prefix of the id is enough. The current
+dictionary is backed up first, so a restore can be undone.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		lock, err := dict.LockFile(userDictFile, lockTimeout)
+		if err == nil {
+			defer lock.Unlock()
+		} else if !lockUnsupported(err, userDictFile) {
+			return err
+		}
+
+		backup, err := dict.RestoreBackup(userDictFile, args[0], backupCount)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Restored %s from backup %s.\n", userDictFile, backup.ID)
+
+		if !noDeploy {
+			fmt.Println("Triggering Rime redeployment...")
+			if err := runDeployCommand(); err != nil {
+				return fmt.Errorf("deployment failed: %w", err)
+			}
+			fmt.Println("Deployment command executed.")
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	backupCmd.AddCommand(backupListCmd)
+	rootCmd.AddCommand(backupCmd)
+	rootCmd.AddCommand(restoreCmd)
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+	"github.com/tenfyzhong/rime-dict-manager/dict"
+)
+
+var charCmd = &cobra.Command{
+	Use:   "char",
+	Short: "Manage the character overrides used for code generation",
+	Long: `Manages the character override file. The codes it gives a character
+replace those of the main dictionary and of --char-dict when codes are
+generated, which helps with characters the main dictionary lacks or
+whose full code should be forced. Rime itself does not read the file.`,
+}
+
+var charSetCmd = &cobra.Command{
+	Use:   "set [char] [code]",
+	Short: "Set the full code of a character",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		char, code := args[0], args[1]
+		if utf8.RuneCountInString(char) != 1 {
+			return fmt.Errorf("'%s' is not a single character", char)
+		}
+		if err := ensureCharsFile(); err != nil {
+			return err
+		}
+
+		err := updateDict(dict.NewDictionary(charsFilePath()), func(d *dict.Dictionary) error {
+			var entries []dict.Entry
+			for _, entry := range d.Entries {
+				if entry.Word != char {
+					entries = append(entries, entry)
+				}
+			}
+			d.Entries = append(entries, dict.Entry{Word: char, Code: code, Dirty: true})
+			fmt.Printf("Setting the code of '%s' to %s...\n", char, code)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Println("Successfully saved.")
+		return nil
+	},
+}
+
+var charListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the character overrides",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := charsFilePath()
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			fmt.Printf("No character overrides in %s\n", path)
+			return nil
+		}
+		d := dict.NewDictionary(path)
+		if err := loadDict(d); err != nil {
+			return err
+		}
+
+		fmt.Printf("Character overrides in %s:\n", path)
+		for _, entry := range d.Entries {
+			if !entry.IsComment && !entry.IsGroup && entry.Word != "" {
+				fmt.Printf("  %s\t%s\n", entry.Word, entry.Code)
+			}
+		}
+		return nil
+	},
+}
+
+var charRemoveCmd = &cobra.Command{
+	Use:   "remove [char]",
+	Short: "Remove the override of a character",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		char := args[0]
+		if _, err := os.Stat(charsFilePath()); os.IsNotExist(err) {
+			return fmt.Errorf("character '%s' has no override", char)
+		}
+
+		err := updateDict(dict.NewDictionary(charsFilePath()), func(d *dict.Dictionary) error {
+			var entries []dict.Entry
+			for _, entry := range d.Entries {
+				if entry.Word != char {
+					entries = append(entries, entry)
+				}
+			}
+			if len(entries) == len(d.Entries) {
+				return fmt.Errorf("character '%s' has no override", char)
+			}
+			d.Entries = entries
+			fmt.Printf("Removing the override of '%s'...\n", char)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Println("Successfully saved.")
+		return nil
+	},
+}
+
+// ensureCharsFile creates an empty character override file if there is
+// none yet.
+func ensureCharsFile() error {
+	path := charsFilePath()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory for '%s': %w", path, err)
+	}
+	d := dict.NewDictionary(path)
+	d.Meta.Name = "chars"
+	d.Meta.Version = "1"
+	d.Meta.Columns = []string{"text", "code"}
+	return d.Save()
+}
+
+func init() {
+	charCmd.AddCommand(charSetCmd)
+	charCmd.AddCommand(charListCmd)
+	charCmd.AddCommand(charRemoveCmd)
+	rootCmd.AddCommand(charCmd)
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/spf13/cobra"
+	"github.com/tenfyzhong/rime-dict-manager/dict"
+)
+
+var decodeLimit int
+
+var decodeCmd = &cobra.Command{
+	Use:   "decode [code]",
+	Short: "List the words on a code",
+	Long: `Looks up a code in the user dictionary, the main dictionary and every
+table they import, listing the words on exactly that code and, below them,
+the words on longer codes starting with it. Use it to see what a code
+will show before claiming it for a new word.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		code := args[0]
+
+		index := &dict.CodeIndex{}
+		if _, err := os.Stat(mainDictFile); err == nil {
+			mainIndex, err := newCache().LoadCodeIndex(rimeDir, mainDictFile)
+			if err != nil {
+				return err
+			}
+			index = mainIndex
+		}
+		if _, err := os.Stat(userDictFile); err == nil {
+			// The user dictionary changes too often to be worth caching.
+			view, err := dict.LoadMerged(rimeDir, userDictFile)
+			if err != nil {
+				return err
+			}
+			index = dict.NewCodeIndex(view).Merge(index)
+		}
+
+		exact := index.Lookup(code)
+		if len(exact) == 0 {
+			fmt.Printf("No words on code '%s'\n", code)
+		} else {
+			fmt.Printf("Words on code '%s':\n", code)
+			printDecoded(exact)
+		}
+
+		longer := index.WithPrefix(code)
+		if len(longer) > 0 {
+			fmt.Printf("\nWords on longer codes starting with '%s':\n", code)
+			if decodeLimit > 0 && len(longer) > decodeLimit {
+				printDecoded(longer[:decodeLimit])
+				fmt.Printf("  ... and %d more\n", len(longer)-decodeLimit)
+			} else {
+				printDecoded(longer)
+			}
+		}
+		return nil
+	},
+}
+
+// printDecoded prints entries as aligned word, code, weight and source
+// columns.
+func printDecoded(entries []dict.SourcedEntry) {
+	pad := func(s string, width int) string {
+		return s + strings.Repeat(" ", max(width-runewidth.StringWidth(s), 1))
+	}
+	for _, e := range entries {
+		fmt.Printf("  %s%s%s%s\n", pad(e.Entry.Word, 16), pad(e.Entry.Code, 12), pad(formatWeight(e.Entry.Weight), 8), filepath.Base(e.Source))
+	}
+}
+
+func init() {
+	decodeCmd.Flags().IntVarP(&decodeLimit, "limit", "n", 20, "Maximum number of prefix matches to show; 0 shows all")
+	rootCmd.AddCommand(decodeCmd)
+}
@@ -14,31 +14,29 @@ var deleteCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		wordToDelete := args[0]
 
-		d := dict.NewDictionary(userDictFile)
-		if err := d.Load(); err != nil {
-			return err
-		}
-
-		var newEntries []dict.Entry
-		found := false
-		for _, entry := range d.Entries {
-			if entry.Word == wordToDelete {
-				found = true
-				// Skip this entry to delete it
-				continue
+		err := updateUserDict(func(d *dict.Dictionary) error {
+			var newEntries []dict.Entry
+			found := false
+			for _, entry := range d.Entries {
+				if entry.Word == wordToDelete {
+					found = true
+					// Skip this entry to delete it
+					continue
+				}
+				newEntries = append(newEntries, entry)
 			}
-			newEntries = append(newEntries, entry)
-		}
 
-		if !found {
-			return fmt.Errorf("word '%s' not found in the dictionary", wordToDelete)
-		}
+			if !found {
+				return fmt.Errorf("word '%s' not found in the dictionary", wordToDelete)
+			}
 
-		d.Entries = newEntries
+			d.Entries = newEntries
 
-		fmt.Printf("Deleting word '%s'...\n", wordToDelete)
-		if err := d.Save(); err != nil {
-			return fmt.Errorf("failed to save dictionary: %w", err)
+			fmt.Printf("Deleting word '%s'...\n", wordToDelete)
+			return nil
+		})
+		if err != nil {
+			return err
 		}
 		fmt.Println("Successfully saved.")
 
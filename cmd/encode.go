@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tenfyzhong/rime-dict-manager/dict"
+)
+
+var encodeFormat string
+
+// encodeResult is the outcome of encoding one word.
+type encodeResult struct {
+	Word       string   `json:"word"`
+	Code       string   `json:"code,omitempty"`
+	Candidates []string `json:"candidates,omitempty"` // Set if the code is ambiguous
+	Error      string   `json:"error,omitempty"`
+}
+
+var encodeCmd = &cobra.Command{
+	Use:   "encode [word...]",
+	Short: "Print the codes the encoder gives words, without adding them",
+	Long: `Generates codes for the words given as arguments, or one word per line
+on standard input, with the encoder selected by --scheme, and prints them
+as word/code TSV or JSON. The user dictionary is never read or changed.
+Characters missing from the main dictionary are listed, and the command
+fails if any word could not be encoded.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if encodeFormat != "tsv" && encodeFormat != "json" {
+			return fmt.Errorf("invalid format '%s': must be tsv or json", encodeFormat)
+		}
+
+		words := args
+		if len(words) == 0 {
+			scanner := bufio.NewScanner(cmd.InOrStdin())
+			for scanner.Scan() {
+				if word := strings.TrimSpace(scanner.Text()); word != "" {
+					words = append(words, word)
+				}
+			}
+			if err := scanner.Err(); err != nil {
+				return fmt.Errorf("failed to read words: %w", err)
+			}
+		}
+		if len(words) == 0 {
+			return fmt.Errorf("no words to encode")
+		}
+
+		encoder, err := newEncoder()
+		if err != nil {
+			return fmt.Errorf("could not create %s encoder: %w", scheme, err)
+		}
+
+		var results []encodeResult
+		var missing []rune
+		failed := 0
+		for _, word := range words {
+			result := encodeResult{Word: word}
+			code, err := encoder.GenerateCode(word)
+			var ambiguous *dict.AmbiguousCodeError
+			var missingChar *dict.MissingCharError
+			switch {
+			case errors.As(err, &ambiguous):
+				result.Candidates = ambiguous.Candidates
+				result.Error = err.Error()
+			case errors.As(err, &missingChar):
+				for _, r := range missingChar.Chars {
+					if !slices.Contains(missing, r) {
+						missing = append(missing, r)
+					}
+				}
+				result.Error = err.Error()
+			case err != nil:
+				result.Error = err.Error()
+			default:
+				result.Code = code
+			}
+			if result.Error != "" {
+				failed++
+			}
+			results = append(results, result)
+		}
+
+		if encodeFormat == "json" {
+			out, err := json.MarshalIndent(results, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(out))
+		} else {
+			for _, r := range results {
+				if r.Error == "" {
+					fmt.Printf("%s\t%s\n", r.Word, r.Code)
+				} else {
+					fmt.Fprintf(os.Stderr, "Cannot encode '%s': %s\n", r.Word, r.Error)
+				}
+			}
+		}
+
+		if len(missing) > 0 {
+			chars := make([]string, len(missing))
+			for i, r := range missing {
+				chars[i] = string(r)
+			}
+			fmt.Fprintf(os.Stderr, "Characters missing from %s: %s\n", mainDictFile, strings.Join(chars, " "))
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d words could not be encoded", failed, len(words))
+		}
+		return nil
+	},
+}
+
+func init() {
+	encodeCmd.Flags().StringVar(&encodeFormat, "format", "tsv", "Output format: tsv or json")
+	rootCmd.AddCommand(encodeCmd)
+}
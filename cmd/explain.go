@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tenfyzhong/rime-dict-manager/dict"
+)
+
+var explainCmd = &cobra.Command{
+	Use:   "explain [word]",
+	Short: "Show how the code of a word is derived",
+	Long: `Shows the codes each character of a word has in the main dictionary,
+the rule applied for the word's length, and which letter each pair of the
+rule's formula takes from which character.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		word := args[0]
+
+		encoder, err := newEncoder()
+		if err != nil {
+			return fmt.Errorf("could not create %s encoder: %w", scheme, err)
+		}
+		explainer, ok := encoder.(dict.Explainer)
+		if !ok {
+			return fmt.Errorf("the %s scheme cannot explain its codes", scheme)
+		}
+		x, err := explainer.Explain(word)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Word: %s (%d characters)\n", x.Word, len(x.Chars))
+		if x.Rule != nil {
+			fmt.Printf("Rule: %s\n", x.Rule)
+		} else {
+			fmt.Println("Rule: none, a single character takes its full code")
+		}
+
+		fmt.Println("\nCharacters:")
+		for _, c := range x.Chars {
+			line := fmt.Sprintf("  %c  full code %s", c.Char, orNone(c.FullCodes))
+			if len(c.OtherCodes) > 0 {
+				line += fmt.Sprintf(", other codes %s", strings.Join(c.OtherCodes, " "))
+			}
+			fmt.Println(line)
+		}
+
+		if len(x.Picks) > 0 {
+			fmt.Println("\nLetters:")
+			for _, p := range x.Picks {
+				switch {
+				case p.CharIndex < 0:
+					fmt.Printf("  %s  skipped, the word has no such character\n", p.Pair)
+				case p.Skipped:
+					fmt.Printf("  %s  %c (%s) skipped, no new letter at that position\n", p.Pair, p.Char, p.FullCode)
+				default:
+					fmt.Printf("  %s  %c (%s) letter %d -> %s\n", p.Pair, p.Char, p.FullCode, p.CodeIndex+1, p.Letter)
+				}
+			}
+		}
+
+		fmt.Printf("\nCode: %s\n", x.Code)
+		if len(x.Candidates) > 0 {
+			fmt.Printf("Ambiguous: a character has several full codes, so the word could be %s. The letters above use the first full code of each character.\n", strings.Join(x.Candidates, ", "))
+		}
+		return nil
+	},
+}
+
+// orNone joins codes, or says there are none.
+func orNone(codes []string) string {
+	if len(codes) == 0 {
+		return "(none)"
+	}
+	return strings.Join(codes, " / ")
+}
+
+func init() {
+	rootCmd.AddCommand(explainCmd)
+}
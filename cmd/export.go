@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tenfyzhong/rime-dict-manager/dict"
+)
+
+var (
+	exportFormat     string
+	exportOutput     string
+	exportSource     string
+	exportGroups     []string
+	exportWord       string
+	exportCodePrefix string
+	exportMinWeight  string
+	exportMaxWeight  string
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Write dictionary entries as JSON, CSV, TSV or a Rime dictionary",
+	Long: `Writes the entries of the user dictionary, or of --source and the tables
+it imports, to standard output or to the file given with --output. The
+entries can be narrowed down by group, word pattern, code prefix and
+weight range. The rime format writes a standalone .dict.yaml, named after
+the output file.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format := exportFormat
+		if format == "" {
+			format = exportFormatFor(exportOutput)
+		}
+		if format != "rime" && !slices.Contains(dict.ExportFormats, format) {
+			return fmt.Errorf("invalid format '%s': must be one of %s, rime", format, strings.Join(dict.ExportFormats, ", "))
+		}
+
+		filter := dict.EntryFilter{Groups: exportGroups, CodePrefix: exportCodePrefix}
+		if exportWord != "" {
+			re, err := regexp.Compile(exportWord)
+			if err != nil {
+				return fmt.Errorf("invalid word pattern: %w", err)
+			}
+			filter.Word = re
+		}
+		var err error
+		if filter.MinWeight, err = dict.ParseWeight(exportMinWeight); err != nil {
+			return fmt.Errorf("invalid --min-weight: %w", err)
+		}
+		if filter.MaxWeight, err = dict.ParseWeight(exportMaxWeight); err != nil {
+			return fmt.Errorf("invalid --max-weight: %w", err)
+		}
+		if !filter.MinWeight.IsAbsent() && !filter.MaxWeight.IsAbsent() && filter.MinWeight.Kind != filter.MaxWeight.Kind {
+			return fmt.Errorf("--min-weight and --max-weight must both be numbers or both be percentages")
+		}
+
+		source := exportSource
+		if source == "" {
+			source = userDictFile
+		}
+		view, err := dict.LoadMerged(rimeDir, source)
+		if err != nil {
+			return err
+		}
+		view = view.Filter(filter)
+
+		if format == "rime" {
+			name := view.Root.Meta.Name
+			if exportOutput != "" {
+				name = strings.TrimSuffix(filepath.Base(exportOutput), ".dict.yaml")
+			}
+			d := view.Flatten(exportOutput, name)
+			if exportOutput == "" {
+				_, err := d.WriteTo(os.Stdout)
+				return err
+			}
+			if err := d.Save(); err != nil {
+				return fmt.Errorf("failed to save dictionary: %w", err)
+			}
+		} else {
+			out := os.Stdout
+			if exportOutput != "" {
+				if out, err = os.Create(exportOutput); err != nil {
+					return fmt.Errorf("failed to create output file: %w", err)
+				}
+			}
+			err := dict.WriteEntries(out, format, view.Entries)
+			if out != os.Stdout {
+				if closeErr := out.Close(); err == nil {
+					err = closeErr
+				}
+			}
+			if err != nil {
+				return fmt.Errorf("failed to write entries: %w", err)
+			}
+		}
+
+		if exportOutput != "" {
+			fmt.Printf("Exported %d entries to %s\n", len(view.Entries), exportOutput)
+		}
+		return nil
+	},
+}
+
+// exportFormatFor guesses the format from the output file name, using
+// TSV if it gives no hint.
+func exportFormatFor(output string) string {
+	switch {
+	case strings.HasSuffix(output, ".dict.yaml"):
+		return "rime"
+	case strings.HasSuffix(output, ".json"):
+		return "json"
+	case strings.HasSuffix(output, ".csv"):
+		return "csv"
+	}
+	return "tsv"
+}
+
+func init() {
+	exportCmd.Flags().StringVar(&exportFormat, "format", "", "Output format: json, csv, tsv or rime (default: from the --output extension, else tsv)")
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "File to write to instead of standard output")
+	exportCmd.Flags().StringVarP(&exportSource, "source", "s", "", "Dictionary to export (defaults to --file)")
+	exportCmd.Flags().StringSliceVarP(&exportGroups, "group", "g", nil, "Only export entries of this group; may be repeated")
+	exportCmd.Flags().StringVar(&exportWord, "word", "", "Only export words matching this regular expression")
+	exportCmd.Flags().StringVar(&exportCodePrefix, "code-prefix", "", "Only export entries whose code starts with this")
+	exportCmd.Flags().StringVar(&exportMinWeight, "min-weight", "", "Only export entries weighing at least this, e.g. 100 or 10%; a percentage only matches percentage weights")
+	exportCmd.Flags().StringVar(&exportMaxWeight, "max-weight", "", "Only export entries weighing at most this, e.g. 100 or 10%")
+	rootCmd.AddCommand(exportCmd)
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tenfyzhong/rime-dict-manager/dict"
+)
+
+var flattenSource string
+
+var flattenCmd = &cobra.Command{
+	Use:   "flatten [output]",
+	Short: "Merge a dictionary and its import_tables into one file",
+	Long: `Loads a dictionary (the main dictionary by default) and every table it
+pulls in through import_tables, and writes all their entries to a single
+standalone dictionary. The dictionary name is taken from the output file name.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		output := args[0]
+		source := flattenSource
+		if source == "" {
+			source = mainDictFile
+		}
+
+		view, err := dict.LoadMerged(rimeDir, source)
+		if err != nil {
+			return err
+		}
+
+		name := strings.TrimSuffix(filepath.Base(output), ".dict.yaml")
+		d := view.Flatten(output, name)
+
+		fmt.Printf("Writing %d entries from %d files to %s...\n", len(view.Entries), len(view.Sources), output)
+		if err := d.Save(); err != nil {
+			return fmt.Errorf("failed to save dictionary: %w", err)
+		}
+		fmt.Println("Successfully saved.")
+
+		return nil
+	},
+}
+
+func init() {
+	flattenCmd.Flags().StringVarP(&flattenSource, "source", "s", "", "Dictionary to flatten (defaults to --main-dict)")
+	rootCmd.AddCommand(flattenCmd)
+}
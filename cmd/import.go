@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tenfyzhong/rime-dict-manager/dict"
+)
+
+var (
+	importFormat     string
+	importOnConflict string
+	importGroup      string
+	importWeight     string
+	importDryRun     bool
+)
+
+// errNothingToImport stops an import that would leave the dictionary as
+// it is from saving it.
+var errNothingToImport = errors.New("nothing to import")
+
+var importCmd = &cobra.Command{
+	Use:   "import [file|-]",
+	Short: "Add the words of a word list to the user dictionary",
+	Long: `Adds every word of a word list to the user dictionary, with a single
+save and redeployment at the end. The list is read from a file, or from
+standard input if the file is '-'. It may be a plain list of words, TSV
+or CSV with the columns word, code, weight and group, all but the word
+optional. Words without a code are encoded with the encoder selected by
+--scheme. Words already in the dictionary are skipped or updated
+according to --on-conflict.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		source := args[0]
+		if importOnConflict != "skip" && importOnConflict != "update" {
+			return fmt.Errorf("invalid conflict policy '%s': must be skip or update", importOnConflict)
+		}
+		defaultWeight, err := dict.ParseWeight(importWeight)
+		if err != nil {
+			return err
+		}
+
+		var input io.Reader = cmd.InOrStdin()
+		if source != "-" {
+			file, err := os.Open(source)
+			if err != nil {
+				return fmt.Errorf("failed to open word list: %w", err)
+			}
+			defer file.Close()
+			input = file
+		}
+		format := importFormat
+		if format == "auto" {
+			format = "tsv"
+			if strings.EqualFold(filepath.Ext(source), ".csv") {
+				format = "csv"
+			}
+		}
+		rows, err := dict.ParseWordList(input, format)
+		if err != nil {
+			return err
+		}
+
+		// Check and encode every row before the dictionary is locked.
+		type importRow struct {
+			dict.WordListRow
+			weight    dict.Weight
+			hasWeight bool
+			hasGroup  bool
+		}
+		var valid []importRow
+		var failures []string
+		var encoder dict.Encoder
+		for _, row := range rows {
+			r := importRow{WordListRow: row, weight: defaultWeight}
+			if row.Weight != "" {
+				if r.weight, err = dict.ParseWeight(row.Weight); err != nil {
+					failures = append(failures, fmt.Sprintf("line %d (%s): %v", row.Line, row.Word, err))
+					continue
+				}
+				r.hasWeight = true
+			}
+			r.hasGroup = r.Group != ""
+			if !r.hasGroup {
+				r.Group = importGroup
+			}
+			if r.Code == "" {
+				if encoder == nil {
+					if encoder, err = newEncoder(); err != nil {
+						return fmt.Errorf("could not create %s encoder: %w", scheme, err)
+					}
+				}
+				code, err := encoder.GenerateCode(row.Word)
+				var ambiguous *dict.AmbiguousCodeError
+				if errors.As(err, &ambiguous) {
+					failures = append(failures, fmt.Sprintf("line %d (%s): ambiguous code, one of %s", row.Line, row.Word, strings.Join(ambiguous.Candidates, ", ")))
+					continue
+				}
+				if err != nil {
+					failures = append(failures, fmt.Sprintf("line %d (%s): %v", row.Line, row.Word, err))
+					continue
+				}
+				r.Code = code
+			}
+			valid = append(valid, r)
+		}
+
+		var added, updated, skipped int
+		apply := func(d *dict.Dictionary) error {
+			added, updated, skipped = 0, 0, 0
+			for _, r := range valid {
+				var existing *dict.Entry
+				for i := range d.Entries {
+					if !d.Entries[i].IsComment && !d.Entries[i].IsGroup && d.Entries[i].Word == r.Word {
+						existing = &d.Entries[i]
+						break
+					}
+				}
+				action := ""
+				switch {
+				case existing == nil:
+					d.AppendToGroup(dict.Entry{Word: r.Word, Code: r.Code, Weight: r.weight}, r.Group)
+					action = fmt.Sprintf("add     %s\t%s\t%s (%s)", r.Word, r.Code, formatWeight(r.weight), r.Group)
+					added++
+				case importOnConflict == "skip":
+					action = fmt.Sprintf("skip    %s, already in the dictionary", r.Word)
+					skipped++
+				default:
+					weight := r.weight
+					if !r.hasWeight {
+						weight = existing.Weight
+					}
+					d.AddOrUpdate(r.Word, r.Code, weight, r.Group)
+					action = fmt.Sprintf("update  %s\t%s\t%s", r.Word, r.Code, formatWeight(weight))
+					// Only a group named by the row moves the entry; the
+					// --group default is for new entries.
+					if r.hasGroup && d.MoveToGroup(r.Word, r.Group) {
+						action += fmt.Sprintf(" (moved to %s)", r.Group)
+					}
+					updated++
+				}
+				if importDryRun {
+					fmt.Println(action)
+				}
+			}
+			if added+updated == 0 {
+				return errNothingToImport
+			}
+			return nil
+		}
+
+		if importDryRun {
+			d := newUserDict()
+			if err := loadDict(d); err != nil {
+				return err
+			}
+			apply(d)
+		} else if len(valid) > 0 {
+			if err := updateUserDict(apply); err != nil && !errors.Is(err, errNothingToImport) {
+				return err
+			}
+		}
+
+		for _, failure := range failures {
+			fmt.Fprintf(os.Stderr, "Failed: %s\n", failure)
+		}
+		prefix := ""
+		if importDryRun {
+			prefix = "Dry run, nothing saved. "
+		}
+		fmt.Printf("%sAdded: %d, updated: %d, skipped: %d, failed: %d\n", prefix, added, updated, skipped, len(failures))
+
+		if !importDryRun && added+updated > 0 && !noDeploy {
+			fmt.Println("Triggering Rime redeployment...")
+			if err := runDeployCommand(); err != nil {
+				return fmt.Errorf("deployment failed: %w", err)
+			}
+			fmt.Println("Deployment command executed.")
+		}
+
+		if len(failures) > 0 {
+			return fmt.Errorf("%d of %d rows could not be imported", len(failures), len(rows))
+		}
+		return nil
+	},
+}
+
+func init() {
+	importCmd.Flags().StringVar(&importFormat, "format", "auto", "Format of the word list: plain, tsv, csv, or auto to choose csv for .csv files and tsv otherwise")
+	importCmd.Flags().StringVar(&importOnConflict, "on-conflict", "skip", "What to do with words already in the dictionary: skip or update")
+	importCmd.Flags().StringVarP(&importGroup, "group", "g", "个人", "Group for new rows that do not name one; updated entries only move to a group the row names")
+	importCmd.Flags().StringVarP(&importWeight, "weight", "w", "100", "Weight for rows that do not give one, e.g. 100 or 10%")
+	importCmd.Flags().BoolVar(&importDryRun, "dry-run", false, "Show what would be imported without saving")
+	rootCmd.AddCommand(importCmd)
+}
@@ -17,7 +17,7 @@ var listCmd = &cobra.Command{
 	Long:  `Reads and displays all entries in the user dictionary file in an easy-to-read format, presented by group.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		d := dict.NewDictionary(userDictFile)
-		if err := d.Load(); err != nil {
+		if err := loadDict(d); err != nil {
 			log.Fatalf("Failed to load dictionary file: %v", err)
 		}
 
@@ -60,7 +60,7 @@ var listCmd = &cobra.Command{
 			} else if !entry.IsComment && entry.Word != "" {
 				printWithPaddedWidth(entry.Word, wordWidth)
 				printWithPaddedWidth(entry.Code, codeWidth)
-				fmt.Printf("%d\n", entry.Weight)
+				fmt.Println(formatWeight(entry.Weight))
 			}
 		}
 	},
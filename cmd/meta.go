@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/tenfyzhong/rime-dict-manager/dict"
+)
+
+var metaCmd = &cobra.Command{
+	Use:   "meta",
+	Short: "Show the metadata in the dictionary header",
+	Long: `Shows the well-known fields of the user dictionary's YAML header.
+Use the get and set subcommands to read or change a single field.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		d := newUserDict()
+		if err := loadDict(d); err != nil {
+			return err
+		}
+
+		for _, key := range dict.MetadataKeys {
+			value, _ := d.Meta.Get(key)
+			fmt.Printf("%-22s %s\n", key+":", value)
+		}
+		return nil
+	},
+}
+
+var metaGetCmd = &cobra.Command{
+	Use:   "get [key]",
+	Short: "Print a single metadata field",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		d := newUserDict()
+		if err := loadDict(d); err != nil {
+			return err
+		}
+
+		value, err := d.Meta.Get(args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Println(value)
+		return nil
+	},
+}
+
+var metaSetCmd = &cobra.Command{
+	Use:   "set [key] [value]",
+	Short: "Change a metadata field in the dictionary header",
+	Long: `Changes a metadata field in the dictionary header. List fields such as
+columns and import_tables take a comma separated value. An empty value
+removes the field. Other header lines and comments are kept as they are.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key, value := args[0], args[1]
+
+		err := updateUserDict(func(d *dict.Dictionary) error {
+			if err := d.Meta.Set(key, value); err != nil {
+				return err
+			}
+			fmt.Printf("Setting %s to '%s'...\n", key, value)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Println("Successfully saved.")
+
+		if !noDeploy {
+			fmt.Println("Triggering Rime redeployment...")
+			if err := runDeployCommand(); err != nil {
+				return fmt.Errorf("deployment failed: %w", err)
+			}
+			fmt.Println("Deployment command executed.")
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	metaCmd.AddCommand(metaGetCmd)
+	metaCmd.AddCommand(metaSetCmd)
+	rootCmd.AddCommand(metaCmd)
+}
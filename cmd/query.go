@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/tenfyzhong/rime-dict-manager/dict"
@@ -9,39 +11,41 @@ import (
 
 var queryCmd = &cobra.Command{
 	Use:   "query [word]",
-	Short: "Query a word in the user dictionary",
-	Args:  cobra.ExactArgs(1),
+	Short: "Query a word in the user and main dictionaries",
+	Long: `Looks up a word in the user dictionary, the main dictionary and every
+table they pull in through import_tables, showing which file each entry
+comes from.`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		wordToQuery := args[0]
 
-		d := dict.NewDictionary(userDictFile)
-		if err := d.Load(); err != nil {
+		paths := []string{userDictFile}
+		if _, err := os.Stat(mainDictFile); err == nil {
+			paths = append(paths, mainDictFile)
+		}
+		view, err := dict.LoadMerged(rimeDir, paths...)
+		if err != nil {
 			return err
 		}
 
-		found := false
-		currentGroup := "Default" // Default group if no '##' is specified
-		for _, entry := range d.Entries {
-			if entry.IsGroup {
-				currentGroup = entry.Group
-				continue
-			}
-
-			if !entry.IsComment && entry.Word == wordToQuery {
-				if !found {
-					fmt.Printf("Found entries for '%s':\n", wordToQuery)
-					found = true
-				}
-				fmt.Printf("- Word:   %s\n", entry.Word)
-				fmt.Printf("  Code:   %s\n", entry.Code)
-				fmt.Printf("  Weight: %d\n", entry.Weight)
-				fmt.Printf("  Group:  %s\n", currentGroup)
-				fmt.Println("---")
-			}
+		entries := view.Lookup(wordToQuery)
+		if len(entries) == 0 {
+			fmt.Printf("Word '%s' not found in %s\n", wordToQuery, strings.Join(view.Sources, ", "))
+			return nil
 		}
 
-		if !found {
-			fmt.Printf("Word '%s' not found in %s\n", wordToQuery, userDictFile)
+		fmt.Printf("Found entries for '%s':\n", wordToQuery)
+		for _, e := range entries {
+			group := e.Group
+			if group == "" {
+				group = "Default" // Default group if no '##' is specified
+			}
+			fmt.Printf("- Word:   %s\n", e.Entry.Word)
+			fmt.Printf("  Code:   %s\n", e.Entry.Code)
+			fmt.Printf("  Weight: %s\n", formatWeight(e.Entry.Weight))
+			fmt.Printf("  Group:  %s\n", group)
+			fmt.Printf("  Source: %s\n", e.Source)
+			fmt.Println("---")
 		}
 
 		return nil
@@ -1,27 +1,43 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/tenfyzhong/rime-dict-manager/config"
+	"github.com/tenfyzhong/rime-dict-manager/dict"
 )
 
 var (
 	// These variables are now accessible to all commands in the cmd package.
 	userDictFile  string
 	mainDictFile  string
+	rimeDir       string
 	deployCommand string
 	noDeploy      bool
+	backupCount   int
+	lockTimeout   time.Duration
+	schemaFile    string
+	ruleSpec      string
+	scheme        string
+	cacheDir      string
+	noCache       bool
+	charsFile     string
+	charDicts     []string
+	nonHan        string
 )
 
 var rootCmd = &cobra.Command{
 	Use:   "rime-dict-manager",
 	Short: "A CLI tool to manage Rime user dictionaries.",
 	Long: `A command-line tool to query, add, modify, and delete entries
-in a Rime user dictionary file, with automatic Wubi code generation
+in a Rime user dictionary file, with automatic code generation
 and Rime redeployment capabilities.`,
 	Version: config.Version,
 }
@@ -36,12 +52,24 @@ func Execute() {
 }
 
 func init() {
+	defaultRimeDir := os.ExpandEnv("$HOME/Library/Rime")
 	defaultUserDictFile := os.ExpandEnv("$HOME/Library/Rime/wubi86_jidian_user.dict.yaml")
 	defaultMainDictFile := os.ExpandEnv("$HOME/Library/Rime/wubi86_jidian.dict.yaml")
 
 	rootCmd.PersistentFlags().StringVarP(&userDictFile, "file", "f", defaultUserDictFile, "Path to the Rime user dictionary file.")
-	rootCmd.PersistentFlags().StringVar(&mainDictFile, "main-dict", defaultMainDictFile, "Path to the main dictionary for Wubi code generation.")
+	rootCmd.PersistentFlags().StringVar(&mainDictFile, "main-dict", defaultMainDictFile, "Path to the main dictionary for code generation.")
+	rootCmd.PersistentFlags().StringVar(&scheme, "scheme", "wubi86", "Input method used to generate codes, one of: "+strings.Join(dict.Schemes(), ", ")+".")
+	rootCmd.PersistentFlags().StringVar(&schemaFile, "schema", "", "Path to the Rime schema whose encoder rules form phrase codes (default: the main dictionary's schema in --rime-dir, if any).")
+	rootCmd.PersistentFlags().StringVar(&ruleSpec, "rules", "", "Phrase rules overriding the schema's, e.g. '2:AaAbBaBb,3:AaBaCaCb,4-:AaBaCaZa'.")
+	rootCmd.PersistentFlags().StringVar(&rimeDir, "rime-dir", defaultRimeDir, "Path to the Rime user directory, used to resolve import_tables.")
 	rootCmd.PersistentFlags().StringVar(&deployCommand, "deploy-cmd", `/Library/Input\ Methods/Squirrel.app/Contents/MacOS/Squirrel --reload`, "The command to execute for Rime redeployment.")
+	rootCmd.PersistentFlags().IntVar(&backupCount, "backups", 5, "Number of backups of the user dictionary to keep; 0 disables backups.")
+	rootCmd.PersistentFlags().DurationVar(&lockTimeout, "lock-timeout", 10*time.Second, "How long to wait for another process to release the user dictionary.")
+	rootCmd.PersistentFlags().StringVar(&nonHan, "non-han", "reject", "What to do with non-Chinese characters such as the A of 'A股' when generating codes: reject, skip, or letter to code them as their lowercase letter.")
+	rootCmd.PersistentFlags().StringVar(&charsFile, "chars", "", "Path to the character override file, whose codes replace the main dictionary's (default: .rime-dict-manager/chars.dict.yaml next to the user dictionary).")
+	rootCmd.PersistentFlags().StringSliceVar(&charDicts, "char-dict", nil, "Extra single-character dictionaries checked before the main dictionary; may be repeated.")
+	rootCmd.PersistentFlags().StringVar(&cacheDir, "cache-dir", "", "Directory for the parsed main dictionary cache (default: rime-dict-manager in the user cache directory).")
+	rootCmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "Always parse the main dictionary instead of using the cache.")
 	rootCmd.PersistentFlags().BoolVar(&noDeploy, "no-deploy", false, "Disable automatic Rime redeployment after an operation.")
 }
 
@@ -59,3 +87,192 @@ func runDeployCommand() error {
 	fmt.Println(string(output))
 	return nil
 }
+
+// newUserDict returns the user dictionary configured from the global flags.
+func newUserDict() *dict.Dictionary {
+	d := dict.NewDictionary(userDictFile)
+	d.Backups = backupCount
+	return d
+}
+
+// loadDict loads d and reports on stderr the problems that did not stop
+// the load, such as weights that are not numbers.
+func loadDict(d *dict.Dictionary) error {
+	if err := d.Load(); err != nil {
+		return err
+	}
+	for _, warning := range d.Warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s: %s\n", d.Path(), warning)
+	}
+	return nil
+}
+
+// updateUserDict loads the user dictionary, applies update to it and saves
+// the result, as updateDict does.
+func updateUserDict(update func(d *dict.Dictionary) error) error {
+	return updateDict(newUserDict(), update)
+}
+
+// updateDict loads d, applies update to it and saves the result. The
+// dictionary's file lock is held for the whole cycle, so concurrent
+// invocations cannot lose each other's changes. If another program
+// changes the file in the meantime, the dictionary is loaded again and
+// update re-applied; update returning an error then means the change no
+// longer fits the new content.
+func updateDict(d *dict.Dictionary, update func(d *dict.Dictionary) error) error {
+	if err := d.Lock(lockTimeout); err != nil && !lockUnsupported(err, d.Path()) {
+		return err
+	}
+	defer d.Unlock()
+
+	const maxAttempts = 3
+	for attempt := 1; ; attempt++ {
+		if err := loadDict(d); err != nil {
+			return err
+		}
+		if err := update(d); err != nil {
+			if attempt > 1 {
+				return fmt.Errorf("%s changed on disk and the change can no longer be applied: %w", d.Path(), err)
+			}
+			return err
+		}
+		err := d.Save()
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, dict.ErrModifiedExternally) {
+			return fmt.Errorf("failed to save dictionary: %w", err)
+		}
+		if attempt == maxAttempts {
+			return fmt.Errorf("failed to save dictionary: %w", err)
+		}
+		fmt.Printf("%s was changed by another program; reloading and applying the change again...\n", d.Path())
+	}
+}
+
+// lockUnsupported reports whether err says the system cannot lock files,
+// in which case it warns that path is changed without a lock.
+func lockUnsupported(err error, path string) bool {
+	if !errors.Is(err, dict.ErrLockUnsupported) {
+		return false
+	}
+	fmt.Fprintf(os.Stderr, "Warning: %v; %s is not protected against concurrent changes\n", err, path)
+	return true
+}
+
+// loadMainView loads the main dictionary together with the tables it
+// imports, from the cache when they have not changed.
+func loadMainView() (*dict.MergedView, error) {
+	return newCache().LoadMerged(rimeDir, mainDictFile)
+}
+
+// newCache returns the cache configured from the global flags. Without a
+// usable cache directory nothing is cached.
+func newCache() *dict.Cache {
+	if noCache {
+		return &dict.Cache{}
+	}
+	dir := cacheDir
+	if dir == "" {
+		dir, _ = dict.DefaultCacheDir()
+	}
+	return &dict.Cache{Dir: dir}
+}
+
+// newEncoder builds the encoder of --scheme from the main dictionary.
+func newEncoder() (dict.Encoder, error) {
+	view, err := loadMainView()
+	if err != nil {
+		return nil, err
+	}
+	return newEncoderFor(view)
+}
+
+// newEncoderFor builds the encoder of --scheme from a loaded main
+// dictionary, the phrase rules in effect and the character overrides.
+func newEncoderFor(view *dict.MergedView) (dict.Encoder, error) {
+	rules, err := encodingRules()
+	if err != nil {
+		return nil, err
+	}
+	chars, err := charOverrides()
+	if err != nil {
+		return nil, err
+	}
+	policy, err := dict.ParseNonHanPolicy(nonHan)
+	if err != nil {
+		return nil, err
+	}
+	return dict.NewEncoder(scheme, dict.EncoderOptions{Main: view, Rules: rules, Chars: chars, NonHan: policy})
+}
+
+// charsFilePath returns the character override file: --chars, or
+// chars.dict.yaml in the .rime-dict-manager directory next to the user
+// dictionary.
+func charsFilePath() string {
+	if charsFile != "" {
+		return charsFile
+	}
+	return filepath.Join(filepath.Dir(userDictFile), ".rime-dict-manager", "chars.dict.yaml")
+}
+
+// charOverrides returns the character codes that take precedence over the
+// main dictionary: those of the override file, then those of the
+// --char-dict dictionaries.
+func charOverrides() (map[rune][]string, error) {
+	var views []*dict.MergedView
+	if _, err := os.Stat(charsFilePath()); err == nil {
+		view, err := dict.LoadMerged(rimeDir, charsFilePath())
+		if err != nil {
+			return nil, err
+		}
+		views = append(views, view)
+	}
+	if len(charDicts) > 0 {
+		view, err := newCache().LoadMerged(rimeDir, charDicts...)
+		if err != nil {
+			return nil, err
+		}
+		views = append(views, view)
+	}
+	return dict.CollectCharCodes(views...), nil
+}
+
+// encodingRules returns the phrase rules for code generation: --rules if
+// given, else the rules of --schema, else those of the schema named after
+// the main dictionary in the Rime directory, if any.
+func encodingRules() ([]dict.EncodingRule, error) {
+	if ruleSpec != "" {
+		return dict.ParseRuleSpec(ruleSpec)
+	}
+	if schemaFile != "" {
+		rules, err := dict.LoadSchemaRules(schemaFile)
+		if err != nil {
+			return nil, err
+		}
+		if len(rules) == 0 {
+			return nil, fmt.Errorf("schema '%s' declares no encoder rules", schemaFile)
+		}
+		return rules, nil
+	}
+
+	name := strings.TrimSuffix(filepath.Base(mainDictFile), ".dict.yaml")
+	defaultSchema := filepath.Join(rimeDir, name+".schema.yaml")
+	if _, err := os.Stat(defaultSchema); err == nil {
+		rules, err := dict.LoadSchemaRules(defaultSchema)
+		if err != nil {
+			return nil, err
+		}
+		return rules, nil
+	}
+	return nil, nil
+}
+
+// formatWeight formats an entry weight for display, showing "-" for
+// entries without one.
+func formatWeight(w dict.Weight) string {
+	if w.IsAbsent() {
+		return "-"
+	}
+	return w.String()
+}
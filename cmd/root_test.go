@@ -2,11 +2,16 @@ package cmd
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/tenfyzhong/rime-dict-manager/dict"
 )
 
 // setupTests creates a temporary directory, a mock deploy script,
@@ -16,6 +21,7 @@ func setupTests(t *testing.T) (tempDir string, mockDeployPath string) {
 
 	tempDir = t.TempDir()
 	t.Setenv("HOME", tempDir)
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(tempDir, ".cache"))
 
 	// Create a mock Rime directory structure
 	rimeDir := filepath.Join(tempDir, "Library", "Rime")
@@ -119,7 +125,7 @@ func TestAddCommand(t *testing.T) {
 
 	// Create empty user dict and a simple main dict
 	os.WriteFile(userDictPath, []byte("---\n...\n"), 0o644)
-	os.WriteFile(mainDictPath, []byte("测\ty\n试\tf\n"), 0o644)
+	os.WriteFile(mainDictPath, []byte("测\timjh\n试\tyaag\n"), 0o644)
 
 	// Override flags for the test
 	userDictFile = userDictPath
@@ -132,7 +138,7 @@ func TestAddCommand(t *testing.T) {
 	}
 
 	content, _ := os.ReadFile(userDictPath)
-	if !strings.Contains(string(content), "测试\tyf\t50") {
+	if !strings.Contains(string(content), "测试\timya\t50") {
 		t.Errorf("add command did not add the word correctly. File content:\n%s", content)
 	}
 	if !strings.Contains(string(content), "## Test") {
@@ -220,3 +226,792 @@ func TestSetWeightCommand(t *testing.T) {
 		t.Errorf("set-weight did not update the weight. File content:\n%s", fileContent)
 	}
 }
+
+func TestDeleteCommand_InvalidWeight(t *testing.T) {
+	tempDir, mockDeployPath := setupTests(t)
+	dictPath := filepath.Join(tempDir, "Library", "Rime", "test.dict.yaml")
+	os.WriteFile(dictPath, []byte("---\n...\nword1\tcode1\t10\nbroken\tcode2\theavy\nword3\tcode3\tlight\n"), 0o644)
+	userDictFile = dictPath
+	deployCommand = mockDeployPath
+
+	// A malformed weight must not keep the tool from removing its line.
+	if _, err := executeCommand(t, "delete", "broken"); err != nil {
+		t.Fatalf("delete command failed: %v", err)
+	}
+
+	fileContent, _ := os.ReadFile(dictPath)
+	if string(fileContent) != "---\n...\nword1\tcode1\t10\nword3\tcode3\tlight\n" {
+		t.Errorf("Unexpected content after delete:\n%s", fileContent)
+	}
+}
+
+func TestMetaSetCommand(t *testing.T) {
+	tempDir, mockDeployPath := setupTests(t)
+	dictPath := filepath.Join(tempDir, "Library", "Rime", "test.dict.yaml")
+	content := "# header comment\n---\nname: test\nversion: \"1.0\"\ncustom_key: keep me\n...\nword1\tcode1\t10\n"
+	os.WriteFile(dictPath, []byte(content), 0o644)
+	userDictFile = dictPath
+	deployCommand = mockDeployPath
+
+	_, err := executeCommand(t, "meta", "set", "version", "2026.10.16")
+	if err != nil {
+		t.Fatalf("meta set command failed: %v", err)
+	}
+
+	fileContent, _ := os.ReadFile(dictPath)
+	expected := "# header comment\n---\nname: test\nversion: \"2026.10.16\"\ncustom_key: keep me\n...\nword1\tcode1\t10\n"
+	if string(fileContent) != expected {
+		t.Errorf("meta set did not update the header correctly. File content:\n%s", fileContent)
+	}
+
+	_, err = executeCommand(t, "meta", "set", "unknown_key", "x")
+	if err == nil {
+		t.Error("meta set should fail for an unknown key")
+	}
+}
+
+func TestMetaSetCommand_Columns(t *testing.T) {
+	tempDir, mockDeployPath := setupTests(t)
+	dictPath := filepath.Join(tempDir, "Library", "Rime", "test.dict.yaml")
+	os.WriteFile(dictPath, []byte("---\nname: test\n...\n## 组\n中国\tkhlg\t10\n工作\taawt\n"), 0o644)
+	userDictFile = dictPath
+	deployCommand = mockDeployPath
+
+	if _, err := executeCommand(t, "meta", "set", "columns", "text,weight,code"); err != nil {
+		t.Fatalf("meta set command failed: %v", err)
+	}
+
+	fileContent, _ := os.ReadFile(dictPath)
+	if !strings.Contains(string(fileContent), "## 组\n中国\t10\tkhlg\n工作\t\taawt\n") {
+		t.Errorf("Entries were not rewritten in the new column order. File content:\n%s", fileContent)
+	}
+
+	d := dict.NewDictionary(dictPath)
+	if err := d.Load(); err != nil {
+		t.Fatalf("Load() after reordering columns failed: %v", err)
+	}
+	if e := d.Entries[1]; e.Word != "中国" || e.Code != "khlg" || e.Weight != dict.AbsoluteWeight(10) {
+		t.Errorf("Unexpected entry after reload: %+v", e)
+	}
+}
+
+func TestSetWeightCommand_Percent(t *testing.T) {
+	tempDir, mockDeployPath := setupTests(t)
+	dictPath := filepath.Join(tempDir, "Library", "Rime", "test.dict.yaml")
+	os.WriteFile(dictPath, []byte("---\n...\nmy_word\tmy_code\nother\tcode\n"), 0o644)
+	userDictFile = dictPath
+	deployCommand = mockDeployPath
+
+	_, err := executeCommand(t, "set-weight", "my_word", "10%")
+	if err != nil {
+		t.Fatalf("set-weight command failed: %v", err)
+	}
+
+	fileContent, _ := os.ReadFile(dictPath)
+	if string(fileContent) != "---\n...\nmy_word\tmy_code\t10%\nother\tcode\n" {
+		t.Errorf("set-weight did not set the percentage. File content:\n%s", fileContent)
+	}
+
+	_, err = executeCommand(t, "set-weight", "my_word", "heavy")
+	if err == nil {
+		t.Error("set-weight should reject a non-numeric weight")
+	}
+}
+
+func TestQueryCommand_ImportTables(t *testing.T) {
+	tempDir, _ := setupTests(t)
+	rimePath := filepath.Join(tempDir, "Library", "Rime")
+	userDictPath := filepath.Join(rimePath, "user.dict.yaml")
+	mainDictPath := filepath.Join(rimePath, "main.dict.yaml")
+	os.WriteFile(userDictPath, []byte("---\nname: user\n...\n"), 0o644)
+	os.WriteFile(mainDictPath, []byte("---\nname: main\nimport_tables:\n  - extra\n...\n"), 0o644)
+	os.WriteFile(filepath.Join(rimePath, "extra.dict.yaml"), []byte("---\nname: extra\n...\n导入\tnfty\t7\n"), 0o644)
+	userDictFile = userDictPath
+	mainDictFile = mainDictPath
+	rimeDir = rimePath
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	rootCmd.SetArgs([]string{"query", "导入"})
+	err := rootCmd.Execute()
+
+	w.Close()
+	os.Stdout = oldStdout
+	if err != nil {
+		t.Fatalf("query command failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	io.Copy(&buf, r)
+	output := buf.String()
+
+	if !strings.Contains(output, "nfty") || !strings.Contains(output, "extra.dict.yaml") {
+		t.Errorf("query should find entries from imported tables. Got: %s", output)
+	}
+}
+
+func TestFlattenCommand(t *testing.T) {
+	tempDir, _ := setupTests(t)
+	rimePath := filepath.Join(tempDir, "Library", "Rime")
+	mainDictPath := filepath.Join(rimePath, "main.dict.yaml")
+	outputPath := filepath.Join(tempDir, "merged.dict.yaml")
+	os.WriteFile(mainDictPath, []byte("---\nname: main\nimport_tables:\n  - extra\n...\n中\tkhk\n"), 0o644)
+	os.WriteFile(filepath.Join(rimePath, "extra.dict.yaml"), []byte("---\nname: extra\n...\n国\tlgyi\n"), 0o644)
+	mainDictFile = mainDictPath
+	rimeDir = rimePath
+
+	_, err := executeCommand(t, "flatten", outputPath)
+	if err != nil {
+		t.Fatalf("flatten command failed: %v", err)
+	}
+
+	content, _ := os.ReadFile(outputPath)
+	if !strings.Contains(string(content), "name: merged") || strings.Contains(string(content), "import_tables") {
+		t.Errorf("flatten did not write a standalone header. File content:\n%s", content)
+	}
+	if !strings.Contains(string(content), "中\tkhk") || !strings.Contains(string(content), "国\tlgyi") {
+		t.Errorf("flatten did not merge the entries. File content:\n%s", content)
+	}
+}
+
+func TestBackupAndRestoreCommands(t *testing.T) {
+	tempDir, mockDeployPath := setupTests(t)
+	dictPath := filepath.Join(tempDir, "Library", "Rime", "test.dict.yaml")
+	os.WriteFile(dictPath, []byte("---\n...\nmy_word\tmy_code\t10\n"), 0o644)
+	userDictFile = dictPath
+	deployCommand = mockDeployPath
+	backupCount = 5
+
+	if _, err := executeCommand(t, "set-weight", "my_word", "20"); err != nil {
+		t.Fatalf("set-weight command failed: %v", err)
+	}
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	rootCmd.SetArgs([]string{"backup", "list"})
+	err := rootCmd.Execute()
+
+	w.Close()
+	os.Stdout = oldStdout
+	if err != nil {
+		t.Fatalf("backup list command failed: %v", err)
+	}
+
+	backups, _ := dict.ListBackups(dictPath)
+	if len(backups) != 1 {
+		t.Fatalf("Expected 1 backup, got %d", len(backups))
+	}
+	var buf bytes.Buffer
+	io.Copy(&buf, r)
+	if !strings.Contains(buf.String(), backups[0].ID) {
+		t.Errorf("backup list should show the backup id. Got: %s", buf.String())
+	}
+
+	if _, err := executeCommand(t, "restore", backups[0].ID); err != nil {
+		t.Fatalf("restore command failed: %v", err)
+	}
+	fileContent, _ := os.ReadFile(dictPath)
+	if string(fileContent) != "---\n...\nmy_word\tmy_code\t10\n" {
+		t.Errorf("restore did not bring back the old content. File content:\n%s", fileContent)
+	}
+}
+
+func TestSetWeightCommand_Locked(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("File locks are only enforced on Unix systems")
+	}
+	tempDir, mockDeployPath := setupTests(t)
+	dictPath := filepath.Join(tempDir, "Library", "Rime", "test.dict.yaml")
+	os.WriteFile(dictPath, []byte("---\n...\nmy_word\tmy_code\t10\n"), 0o644)
+	userDictFile = dictPath
+	deployCommand = mockDeployPath
+
+	lock, err := dict.LockFile(dictPath, time.Second)
+	if err != nil {
+		t.Fatalf("LockFile() failed: %v", err)
+	}
+	defer lock.Unlock()
+
+	defer func() { lockTimeout = 10 * time.Second }()
+	_, err = executeCommand(t, "set-weight", "my_word", "20", "--lock-timeout", "100ms")
+	if !errors.Is(err, dict.ErrLockTimeout) {
+		t.Errorf("set-weight should fail while the dictionary is locked, got: %v", err)
+	}
+
+	fileContent, _ := os.ReadFile(dictPath)
+	if !strings.Contains(string(fileContent), "my_word\tmy_code\t10") {
+		t.Errorf("set-weight changed a locked dictionary. File content:\n%s", fileContent)
+	}
+}
+
+func TestUpdateUserDict_ExternalEdit(t *testing.T) {
+	tempDir, _ := setupTests(t)
+	dictPath := filepath.Join(tempDir, "Library", "Rime", "test.dict.yaml")
+	os.WriteFile(dictPath, []byte("---\n...\nmy_word\tmy_code\t10\n"), 0o644)
+	userDictFile = dictPath
+
+	attempts := 0
+	err := updateUserDict(func(d *dict.Dictionary) error {
+		attempts++
+		if attempts == 1 {
+			// Another program appends a word while we hold the dictionary.
+			os.WriteFile(dictPath, []byte("---\n...\nmy_word\tmy_code\t10\nnew_word\tnew_code\t1\n"), 0o644)
+		}
+		d.AddOrUpdate("my_word", "my_code", dict.AbsoluteWeight(99), "")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("updateUserDict() failed: %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("Expected the change to be applied twice, got %d attempts", attempts)
+	}
+
+	fileContent, _ := os.ReadFile(dictPath)
+	if string(fileContent) != "---\n...\nmy_word\tmy_code\t99\nnew_word\tnew_code\t1\n" {
+		t.Errorf("The external edit or the change was lost. File content:\n%s", fileContent)
+	}
+}
+
+func TestUpdateUserDict_ChangeNoLongerApplies(t *testing.T) {
+	tempDir, _ := setupTests(t)
+	dictPath := filepath.Join(tempDir, "Library", "Rime", "test.dict.yaml")
+	os.WriteFile(dictPath, []byte("---\n...\nmy_word\tmy_code\t10\n"), 0o644)
+	userDictFile = dictPath
+
+	attempts := 0
+	err := updateUserDict(func(d *dict.Dictionary) error {
+		attempts++
+		if attempts == 1 {
+			// Another program removes the word we are about to change.
+			os.WriteFile(dictPath, []byte("---\n...\n"), 0o644)
+			return nil
+		}
+		return errors.New("word 'my_word' not found in the dictionary")
+	})
+	if err == nil || !strings.Contains(err.Error(), "changed on disk") {
+		t.Errorf("Expected an error explaining the conflict, got: %v", err)
+	}
+
+	fileContent, _ := os.ReadFile(dictPath)
+	if string(fileContent) != "---\n...\n" {
+		t.Errorf("The external edit was overwritten. File content:\n%s", fileContent)
+	}
+}
+
+func TestAddCommand_AmbiguousCode(t *testing.T) {
+	tempDir, mockDeployPath := setupTests(t)
+	userDictPath := filepath.Join(tempDir, "Library", "Rime", "user.dict.yaml")
+	mainDictPath := filepath.Join(tempDir, "Library", "Rime", "main.dict.yaml")
+	os.WriteFile(userDictPath, []byte("---\n...\n"), 0o644)
+	os.WriteFile(mainDictPath, []byte("甲\tabcd\n甲\tefgh\n丙\tgmwi\n"), 0o644)
+	userDictFile = userDictPath
+	mainDictFile = mainDictPath
+	deployCommand = mockDeployPath
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	rootCmd.SetArgs([]string{"add", "甲丙"})
+	err := rootCmd.Execute()
+
+	w.Close()
+	os.Stdout = oldStdout
+
+	if err == nil || !strings.Contains(err.Error(), "--code") {
+		t.Errorf("add should refuse to guess an ambiguous code, got: %v", err)
+	}
+	var buf bytes.Buffer
+	io.Copy(&buf, r)
+	if !strings.Contains(buf.String(), "abgm") || !strings.Contains(buf.String(), "efgm") {
+		t.Errorf("add should list the candidate codes. Got: %s", buf.String())
+	}
+
+	content, _ := os.ReadFile(userDictPath)
+	if strings.Contains(string(content), "甲丙") {
+		t.Errorf("add should not write an ambiguous word. File content:\n%s", content)
+	}
+}
+
+func TestAddCommand_SchemaRules(t *testing.T) {
+	tempDir, mockDeployPath := setupTests(t)
+	rimePath := filepath.Join(tempDir, "Library", "Rime")
+	userDictPath := filepath.Join(rimePath, "user.dict.yaml")
+	mainDictPath := filepath.Join(rimePath, "cangjie5.dict.yaml")
+	os.WriteFile(userDictPath, []byte("---\n...\n"), 0o644)
+	os.WriteFile(mainDictPath, []byte("中\tl\n國\twirm\n"), 0o644)
+	os.WriteFile(filepath.Join(rimePath, "cangjie5.schema.yaml"), []byte("encoder:\n  rules:\n    - {length_equal: 2, formula: \"AaAzBaBbBz\"}\n"), 0o644)
+	userDictFile = userDictPath
+	mainDictFile = mainDictPath
+	rimeDir = rimePath
+	deployCommand = mockDeployPath
+
+	_, err := executeCommand(t, "add", "中國")
+	if err != nil {
+		t.Fatalf("add command failed: %v", err)
+	}
+	content, _ := os.ReadFile(userDictPath)
+	if !strings.Contains(string(content), "中國\tlwim") {
+		t.Errorf("add should use the schema's rules. File content:\n%s", content)
+	}
+
+	ruleSpec = "2:AaBa"
+	defer func() { ruleSpec = "" }()
+	_, err = executeCommand(t, "add", "中國")
+	if err != nil {
+		t.Fatalf("add command failed: %v", err)
+	}
+	content, _ = os.ReadFile(userDictPath)
+	if !strings.Contains(string(content), "中國\tlw") {
+		t.Errorf("add should prefer --rules over the schema. File content:\n%s", content)
+	}
+}
+
+func TestAddCommand_UnknownScheme(t *testing.T) {
+	tempDir, mockDeployPath := setupTests(t)
+	userDictPath := filepath.Join(tempDir, "Library", "Rime", "user.dict.yaml")
+	mainDictPath := filepath.Join(tempDir, "Library", "Rime", "main.dict.yaml")
+	os.WriteFile(userDictPath, []byte("---\n...\n"), 0o644)
+	os.WriteFile(mainDictPath, []byte("测\timjh\n试\tyaag\n"), 0o644)
+	userDictFile = userDictPath
+	mainDictFile = mainDictPath
+	deployCommand = mockDeployPath
+
+	scheme = "dvorak"
+	defer func() { scheme = "wubi86" }()
+	_, err := executeCommand(t, "add", "测试")
+	if err == nil || !strings.Contains(err.Error(), "unknown scheme") {
+		t.Errorf("add should reject an unknown scheme, got: %v", err)
+	}
+}
+
+func TestAddCommand_Pinyin(t *testing.T) {
+	tempDir, mockDeployPath := setupTests(t)
+	userDictPath := filepath.Join(tempDir, "Library", "Rime", "luna_pinyin.custom.dict.yaml")
+	mainDictPath := filepath.Join(tempDir, "Library", "Rime", "luna_pinyin.dict.yaml")
+	os.WriteFile(userDictPath, []byte("---\nname: luna_pinyin.custom\n...\n"), 0o644)
+	os.WriteFile(mainDictPath, []byte("---\nname: luna_pinyin\n...\n银\tyin\n行\txing\n行\thang\n银行\tyin hang\n"), 0o644)
+	userDictFile = userDictPath
+	mainDictFile = mainDictPath
+	deployCommand = mockDeployPath
+
+	scheme = "pinyin"
+	defer func() { scheme = "wubi86" }()
+	_, err := executeCommand(t, "add", "银行", "--group", "Test")
+	if err != nil {
+		t.Fatalf("add command failed: %v", err)
+	}
+
+	content, _ := os.ReadFile(userDictPath)
+	if !strings.Contains(string(content), "银行\tyin hang\t") {
+		t.Errorf("add did not generate the pinyin code. File content:\n%s", content)
+	}
+}
+
+func TestDecodeCommand(t *testing.T) {
+	tempDir, _ := setupTests(t)
+	rimePath := filepath.Join(tempDir, "Library", "Rime")
+	userDictPath := filepath.Join(rimePath, "user.dict.yaml")
+	mainDictPath := filepath.Join(rimePath, "main.dict.yaml")
+	os.WriteFile(userDictPath, []byte("---\nname: user\n...\n口子\tkhlg\t50\n"), 0o644)
+	os.WriteFile(mainDictPath, []byte("---\nname: main\n...\n中\tkhk\n中国\tkhlg\t10\n中华\tkhwx\n"), 0o644)
+	userDictFile = userDictPath
+	mainDictFile = mainDictPath
+	rimeDir = rimePath
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	rootCmd.SetArgs([]string{"decode", "khlg"})
+	err := rootCmd.Execute()
+	rootCmd.SetArgs([]string{"decode", "kh"})
+	err2 := rootCmd.Execute()
+
+	w.Close()
+	os.Stdout = oldStdout
+	if err != nil || err2 != nil {
+		t.Fatalf("decode command failed: %v, %v", err, err2)
+	}
+
+	var buf bytes.Buffer
+	io.Copy(&buf, r)
+	output := buf.String()
+	for _, want := range []string{"口子", "user.dict.yaml", "中国", "main.dict.yaml", "No words on code 'kh'", "中华"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("decode output is missing %q. Got: %s", want, output)
+		}
+	}
+	if strings.Index(output, "口子") > strings.Index(output, "中国") {
+		t.Errorf("decode should list heavier words first. Got: %s", output)
+	}
+}
+
+func TestEncodeCommand(t *testing.T) {
+	tempDir, _ := setupTests(t)
+	userDictPath := filepath.Join(tempDir, "Library", "Rime", "user.dict.yaml")
+	mainDictPath := filepath.Join(tempDir, "Library", "Rime", "main.dict.yaml")
+	os.WriteFile(userDictPath, []byte("---\n...\n"), 0o644)
+	os.WriteFile(mainDictPath, []byte("测\timjh\n试\tyaag\n工\taaaa\n作\twthf\n"), 0o644)
+	userDictFile = userDictPath
+	mainDictFile = mainDictPath
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	rootCmd.SetArgs([]string{"encode", "测试", "工作"})
+	err := rootCmd.Execute()
+
+	// Words from stdin, one of them with a character the main dict lacks.
+	rootCmd.SetIn(strings.NewReader("测试\n\n工人\n人员\n"))
+	defer rootCmd.SetIn(nil)
+	rootCmd.SetArgs([]string{"encode", "--format", "json"})
+	errMissing := rootCmd.Execute()
+	encodeFormat = "tsv"
+
+	w.Close()
+	os.Stdout = oldStdout
+
+	if err != nil {
+		t.Fatalf("encode command failed: %v", err)
+	}
+	if errMissing == nil {
+		t.Error("encode should fail when a word cannot be encoded")
+	}
+
+	var buf bytes.Buffer
+	io.Copy(&buf, r)
+	output := buf.String()
+	if !strings.Contains(output, "测试\timya\n工作\taawt\n") {
+		t.Errorf("encode did not print the codes as TSV. Got: %s", output)
+	}
+	if !strings.Contains(output, `"code": "imya"`) || !strings.Contains(output, `"error": "character '人' not found in main dictionary"`) {
+		t.Errorf("encode did not print JSON results. Got: %s", output)
+	}
+	if !strings.Contains(output, `"error": "characters '人', '员' not found in main dictionary"`) {
+		t.Errorf("encode should name every missing character of a word. Got: %s", output)
+	}
+
+	content, _ := os.ReadFile(userDictPath)
+	if string(content) != "---\n...\n" {
+		t.Errorf("encode must not modify the user dictionary. File content:\n%s", content)
+	}
+}
+
+func TestExplainCommand(t *testing.T) {
+	tempDir, _ := setupTests(t)
+	mainDictPath := filepath.Join(tempDir, "Library", "Rime", "main.dict.yaml")
+	os.WriteFile(mainDictPath, []byte("中\tkhk\n中\tk\n国\tlgyi\n人\twwww\n"), 0o644)
+	mainDictFile = mainDictPath
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	rootCmd.SetArgs([]string{"explain", "中国人"})
+	err := rootCmd.Execute()
+
+	w.Close()
+	os.Stdout = oldStdout
+	if err != nil {
+		t.Fatalf("explain command failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	io.Copy(&buf, r)
+	output := buf.String()
+	for _, want := range []string{"Rule: 3:AaBaCaCb", "中  full code khk, other codes k", "Cb  人 (wwww) letter 2 -> w", "Code: klww"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("explain output is missing %q. Got: %s", want, output)
+		}
+	}
+}
+
+func TestVerifyEncoderCommand(t *testing.T) {
+	tempDir, _ := setupTests(t)
+	mainDictPath := filepath.Join(tempDir, "Library", "Rime", "main.dict.yaml")
+	os.WriteFile(mainDictPath, []byte("中\tkhk\n国\tlgyi\n工\taaaa\n作\twthf\n中国\tkhlg\n工作\taawt\n"), 0o644)
+	mainDictFile = mainDictPath
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	ruleSpec = "2:AaBaAbBb"
+	defer func() { ruleSpec = "" }()
+	rootCmd.SetArgs([]string{"verify-encoder"})
+	err := rootCmd.Execute()
+
+	w.Close()
+	os.Stdout = oldStdout
+	if err != nil {
+		t.Fatalf("verify-encoder command failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	io.Copy(&buf, r)
+	output := buf.String()
+	for _, want := range []string{"100.00%", "中国  dictionary khlg, encoder klhg", "2:AaAbBaBb fits 100.00%"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("verify-encoder output is missing %q. Got: %s", want, output)
+		}
+	}
+}
+
+func TestCharCommands(t *testing.T) {
+	tempDir, mockDeployPath := setupTests(t)
+	userDictPath := filepath.Join(tempDir, "Library", "Rime", "user.dict.yaml")
+	mainDictPath := filepath.Join(tempDir, "Library", "Rime", "main.dict.yaml")
+	os.WriteFile(userDictPath, []byte("---\n...\n"), 0o644)
+	os.WriteFile(mainDictPath, []byte("中\tkhk\n"), 0o644)
+	userDictFile = userDictPath
+	mainDictFile = mainDictPath
+	deployCommand = mockDeployPath
+
+	// The main dict lacks 丼, so add fails until it gets an override.
+	if _, err := executeCommand(t, "add", "中丼"); err == nil {
+		t.Fatal("add should fail for a character missing from the main dict")
+	}
+	if _, err := executeCommand(t, "char", "set", "丼", "fjfj"); err != nil {
+		t.Fatalf("char set failed: %v", err)
+	}
+	if _, err := executeCommand(t, "add", "中丼"); err != nil {
+		t.Fatalf("add command failed: %v", err)
+	}
+	content, _ := os.ReadFile(userDictPath)
+	if !strings.Contains(string(content), "中丼\tkhfj") {
+		t.Errorf("add should use the character override. File content:\n%s", content)
+	}
+
+	charsPath := filepath.Join(tempDir, "Library", "Rime", ".rime-dict-manager", "chars.dict.yaml")
+	if _, err := executeCommand(t, "char", "remove", "丼"); err != nil {
+		t.Fatalf("char remove failed: %v", err)
+	}
+	content, _ = os.ReadFile(charsPath)
+	if strings.Contains(string(content), "丼") {
+		t.Errorf("char remove did not remove the override. File content:\n%s", content)
+	}
+	if _, err := executeCommand(t, "char", "remove", "丼"); err == nil {
+		t.Error("char remove should fail for a character without an override")
+	}
+}
+
+func TestAddCommand_NonHan(t *testing.T) {
+	tempDir, mockDeployPath := setupTests(t)
+	userDictPath := filepath.Join(tempDir, "Library", "Rime", "user.dict.yaml")
+	mainDictPath := filepath.Join(tempDir, "Library", "Rime", "main.dict.yaml")
+	os.WriteFile(userDictPath, []byte("---\n...\n"), 0o644)
+	os.WriteFile(mainDictPath, []byte("线\txgt\n图\tltui\n"), 0o644)
+	userDictFile = userDictPath
+	mainDictFile = mainDictPath
+	deployCommand = mockDeployPath
+
+	if _, err := executeCommand(t, "add", "K线图"); err == nil {
+		t.Fatal("add should reject non-Chinese characters by default")
+	}
+
+	nonHan = "letter"
+	defer func() { nonHan = "reject" }()
+	if _, err := executeCommand(t, "add", "Ｋ线图"); err != nil {
+		t.Fatalf("add command failed: %v", err)
+	}
+	content, _ := os.ReadFile(userDictPath)
+	if !strings.Contains(string(content), "Ｋ线图\tkxlt") {
+		t.Errorf("add should code the letter as itself. File content:\n%s", content)
+	}
+}
+
+func TestImportCommand(t *testing.T) {
+	tempDir, mockDeployPath := setupTests(t)
+	userDictPath := filepath.Join(tempDir, "Library", "Rime", "user.dict.yaml")
+	mainDictPath := filepath.Join(tempDir, "Library", "Rime", "main.dict.yaml")
+	listPath := filepath.Join(tempDir, "words.tsv")
+	original := "---\n...\n中国\tkhlg\t5\n"
+	os.WriteFile(userDictPath, []byte(original), 0o644)
+	os.WriteFile(mainDictPath, []byte("测\timjh\n试\tyaag\n"), 0o644)
+	os.WriteFile(listPath, []byte("测试\n工作\taawt\t20\n中国\tkkkk\t\t新组\n测验\n"), 0o644)
+	userDictFile = userDictPath
+	mainDictFile = mainDictPath
+	deployCommand = mockDeployPath
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	importDryRun = true
+	rootCmd.SetArgs([]string{"import", listPath})
+	errDryRun := rootCmd.Execute()
+	importDryRun = false
+	dryRunContent, _ := os.ReadFile(userDictPath)
+
+	rootCmd.SetArgs([]string{"import", listPath})
+	errSkip := rootCmd.Execute()
+	skipContent, _ := os.ReadFile(userDictPath)
+
+	importOnConflict = "update"
+	defer func() { importOnConflict = "skip" }()
+	rootCmd.SetIn(strings.NewReader("中国\tkkkk\n"))
+	defer rootCmd.SetIn(nil)
+	rootCmd.SetArgs([]string{"import", "-"})
+	errUpdate := rootCmd.Execute()
+	updateContent, _ := os.ReadFile(userDictPath)
+
+	w.Close()
+	os.Stdout = oldStdout
+	// 测验 cannot be encoded, so the first two imports report a failure.
+	for _, err := range []error{errDryRun, errSkip} {
+		if err == nil || !strings.Contains(err.Error(), "1 of 4 rows could not be imported") {
+			t.Errorf("import should fail when a row cannot be imported, got: %v", err)
+		}
+	}
+	if errUpdate != nil {
+		t.Fatalf("import command failed: %v", errUpdate)
+	}
+
+	var buf bytes.Buffer
+	io.Copy(&buf, r)
+	output := buf.String()
+
+	if string(dryRunContent) != original {
+		t.Errorf("A dry run must not change the dictionary. File content:\n%s", dryRunContent)
+	}
+	if !strings.Contains(output, "Dry run, nothing saved. Added: 2, updated: 0, skipped: 1, failed: 1") {
+		t.Errorf("Unexpected dry run summary. Got: %s", output)
+	}
+	if !strings.Contains(output, "Added: 2, updated: 0, skipped: 1, failed: 1") {
+		t.Errorf("Unexpected import summary. Got: %s", output)
+	}
+	for _, want := range []string{"测试\timya\t100", "工作\taawt\t20", "中国\tkhlg\t5"} {
+		if !strings.Contains(string(skipContent), want) {
+			t.Errorf("Import is missing %q. File content:\n%s", want, skipContent)
+		}
+	}
+	if strings.Count(output, "Triggering Rime redeployment...") != 2 {
+		t.Errorf("Each import should deploy once. Got: %s", output)
+	}
+
+	if !strings.Contains(output, "Added: 0, updated: 1, skipped: 0, failed: 0") {
+		t.Errorf("Unexpected update summary. Got: %s", output)
+	}
+	if !strings.Contains(string(updateContent), "中国\tkkkk\t5") {
+		t.Errorf("An update without a weight should keep the old one. File content:\n%s", updateContent)
+	}
+}
+
+func TestImportCommand_UpdateGroup(t *testing.T) {
+	tempDir, mockDeployPath := setupTests(t)
+	userDictPath := filepath.Join(tempDir, "Library", "Rime", "user.dict.yaml")
+	os.WriteFile(userDictPath, []byte("---\n...\n## 旧组\n中国\tkhlg\t5\n工作\taawt\t10\n"), 0o644)
+	userDictFile = userDictPath
+	deployCommand = mockDeployPath
+	importOnConflict = "update"
+	defer func() { importOnConflict, importDryRun = "skip", false }()
+	defer rootCmd.SetIn(nil)
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	// 中国 names a group and moves, 工作 does not and stays.
+	list := "中国\tkhlg\t\t新组\n工作\taawt\n"
+	importDryRun = true
+	rootCmd.SetIn(strings.NewReader(list))
+	rootCmd.SetArgs([]string{"import", "-"})
+	errDryRun := rootCmd.Execute()
+	importDryRun = false
+	rootCmd.SetIn(strings.NewReader(list))
+	rootCmd.SetArgs([]string{"import", "-"})
+	errUpdate := rootCmd.Execute()
+
+	w.Close()
+	os.Stdout = oldStdout
+	for _, err := range []error{errDryRun, errUpdate} {
+		if err != nil {
+			t.Fatalf("import command failed: %v", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	io.Copy(&buf, r)
+	if !strings.Contains(buf.String(), "update  中国\tkhlg\t5 (moved to 新组)") || strings.Contains(buf.String(), "工作\taawt\t10 (moved") {
+		t.Errorf("The dry run should tell which entries move. Got: %s", buf.String())
+	}
+	fileContent, _ := os.ReadFile(userDictPath)
+	if expected := "---\n...\n## 旧组\n工作\taawt\t10\n## 新组\n中国\tkhlg\t5\n"; string(fileContent) != expected {
+		t.Errorf("Unexpected content after import:\n%s\nwant:\n%s", fileContent, expected)
+	}
+}
+
+func TestExportCommand(t *testing.T) {
+	tempDir, _ := setupTests(t)
+	userDictPath := filepath.Join(tempDir, "Library", "Rime", "user.dict.yaml")
+	csvPath := filepath.Join(tempDir, "work.csv")
+	rimePath := filepath.Join(tempDir, "heavy.dict.yaml")
+	tsvPath := filepath.Join(tempDir, "percent.tsv")
+	os.WriteFile(userDictPath, []byte("---\nname: user\n...\n## 工作\n用例\tetwg\t200\n解耦\tqedi\t100\n## 个人\n幂等\tpjtf\t150\n散列\tanuj\t5%\n"), 0o644)
+	userDictFile = userDictPath
+	defer func() {
+		exportOutput, exportGroups, exportMinWeight, exportMaxWeight = "", nil, "", ""
+	}()
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	rootCmd.SetArgs([]string{"export", "-g", "工作", "-o", csvPath})
+	errCSV := rootCmd.Execute()
+	exportGroups = nil
+	rootCmd.SetArgs([]string{"export", "--min-weight", "150", "-o", rimePath})
+	errRime := rootCmd.Execute()
+	exportMinWeight = ""
+	rootCmd.SetArgs([]string{"export", "--max-weight", "50%", "-o", tsvPath})
+	errPercent := rootCmd.Execute()
+
+	w.Close()
+	os.Stdout = oldStdout
+	for _, err := range []error{errCSV, errRime, errPercent} {
+		if err != nil {
+			t.Fatalf("export command failed: %v", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	io.Copy(&buf, r)
+	if !strings.Contains(buf.String(), "Exported 2 entries to "+csvPath) {
+		t.Errorf("Unexpected export summary. Got: %s", buf.String())
+	}
+
+	csvContent, _ := os.ReadFile(csvPath)
+	if expected := "word,code,weight,group\n用例,etwg,200,工作\n解耦,qedi,100,工作\n"; string(csvContent) != expected {
+		t.Errorf("Unexpected CSV export. Got:\n%s\nWant:\n%s", csvContent, expected)
+	}
+
+	// A percentage bound only selects percentage weights.
+	tsvContent, _ := os.ReadFile(tsvPath)
+	if expected := "word\tcode\tweight\tgroup\n散列\tanuj\t5%\t个人\n"; string(tsvContent) != expected {
+		t.Errorf("Unexpected TSV export. Got:\n%s\nWant:\n%s", tsvContent, expected)
+	}
+
+	d := dict.NewDictionary(rimePath)
+	if err := d.Load(); err != nil {
+		t.Fatalf("Failed to load the exported dictionary: %v", err)
+	}
+	if d.Meta.Name != "heavy" {
+		t.Errorf("The exported dictionary should be named after its file, got %q", d.Meta.Name)
+	}
+	var words []string
+	for _, e := range d.Entries {
+		if !e.IsComment && !e.IsGroup {
+			words = append(words, e.Word)
+		}
+	}
+	if strings.Join(words, " ") != "用例 幂等" {
+		t.Errorf("Unexpected Rime export: %v", words)
+	}
+}
@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"strconv"
 
 	"github.com/spf13/cobra"
 	"github.com/tenfyzhong/rime-dict-manager/dict"
@@ -14,32 +13,31 @@ var setWeightCmd = &cobra.Command{
 	Args:  cobra.ExactArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		wordToUpdate := args[0]
-		newWeight, err := strconv.Atoi(args[1])
-		if err != nil {
-			return fmt.Errorf("invalid weight value: %s. Must be an integer", args[1])
+		newWeight, err := dict.ParseWeight(args[1])
+		if err != nil || newWeight.IsAbsent() {
+			return fmt.Errorf("invalid weight value: %s. Must be a number or a percentage such as 10%%", args[1])
 		}
 
-		d := dict.NewDictionary(userDictFile)
-		if err := d.Load(); err != nil {
-			return err
-		}
-
-		found := false
-		for i := range d.Entries {
-			if d.Entries[i].Word == wordToUpdate {
-				d.Entries[i].Weight = newWeight
-				found = true
-				// break // Uncomment if you only want to update the first occurrence
+		err = updateUserDict(func(d *dict.Dictionary) error {
+			found := false
+			for i := range d.Entries {
+				if d.Entries[i].Word == wordToUpdate {
+					d.Entries[i].Weight = newWeight
+					d.Entries[i].Dirty = true
+					found = true
+					// break // Uncomment if you only want to update the first occurrence
+				}
 			}
-		}
 
-		if !found {
-			return fmt.Errorf("word '%s' not found in the dictionary", wordToUpdate)
-		}
+			if !found {
+				return fmt.Errorf("word '%s' not found in the dictionary", wordToUpdate)
+			}
 
-		fmt.Printf("Updating weight for '%s' to %d...\n", wordToUpdate, newWeight)
-		if err := d.Save(); err != nil {
-			return fmt.Errorf("failed to save dictionary: %w", err)
+			fmt.Printf("Updating weight for '%s' to %s...\n", wordToUpdate, newWeight)
+			return nil
+		})
+		if err != nil {
+			return err
 		}
 		fmt.Println("Successfully saved.")
 
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tenfyzhong/rime-dict-manager/dict"
+)
+
+var verifyExamples int
+
+var verifyEncoderCmd = &cobra.Command{
+	Use:   "verify-encoder",
+	Short: "Check the encoder against the phrases of the main dictionary",
+	Long: `Encodes every phrase of the main dictionary, and the tables it imports,
+again with the encoder selected by --scheme and compares the result with
+the code the dictionary gives it. The mismatch rate is reported for each
+word length, with examples. For table schemes, the formula that best fits
+the dictionary is suggested for every rule that does not match it.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		view, err := loadMainView()
+		if err != nil {
+			return err
+		}
+		encoder, err := newEncoderFor(view)
+		if err != nil {
+			return fmt.Errorf("could not create %s encoder: %w", scheme, err)
+		}
+
+		reports := dict.VerifyEncoder(encoder, view, verifyExamples)
+		if len(reports) == 0 {
+			fmt.Printf("No phrases found in %s\n", mainDictFile)
+			return nil
+		}
+
+		total, mismatches, failures := 0, 0, 0
+		fmt.Printf("Verifying the %s encoder against %s\n\n", scheme, mainDictFile)
+		fmt.Printf("%-8s %10s %12s %10s %10s\n", "Length", "Phrases", "Mismatches", "Failures", "Rate")
+		for _, r := range reports {
+			fmt.Printf("%-8d %10d %12d %10d %9.2f%%\n", r.Length, r.Phrases, r.Mismatches, r.Failures, 100*r.MismatchRate())
+			total += r.Phrases
+			mismatches += r.Mismatches
+			failures += r.Failures
+		}
+		fmt.Printf("%-8s %10d %12d %10d %9.2f%%\n", "All", total, mismatches, failures, 100*float64(mismatches+failures)/float64(total))
+
+		for _, r := range reports {
+			if len(r.Examples) == 0 {
+				continue
+			}
+			fmt.Printf("\nExamples of %d-character phrases:\n", r.Length)
+			for _, m := range r.Examples {
+				expected := strings.Join(m.Expected, "/")
+				if m.Err != nil {
+					fmt.Printf("  %s  dictionary %s, error: %v\n", m.Word, expected, m.Err)
+				} else {
+					fmt.Printf("  %s  dictionary %s, encoder %s\n", m.Word, expected, m.Got)
+				}
+			}
+		}
+
+		base := encoder
+		if w, ok := encoder.(*dict.NonHanEncoder); ok {
+			base = w.Encoder
+		}
+		table, ok := base.(*dict.TableEncoder)
+		if !ok {
+			return nil
+		}
+		printed := false
+		for _, rule := range table.Rules {
+			rate, phrases := 0.0, 0
+			for _, r := range reports {
+				if rule.Matches(r.Length) {
+					rate += r.MismatchRate() * float64(r.Phrases)
+					phrases += r.Phrases
+				}
+			}
+			if phrases == 0 || rate == 0 {
+				continue
+			}
+			suggested, fit, ok := table.SuggestRule(view, rule)
+			if !ok || suggested.Formula == rule.Formula {
+				continue
+			}
+			if !printed {
+				fmt.Println("\nSuggested rules:")
+				printed = true
+			}
+			fmt.Printf("  %s fits %.2f%% of the phrases (current %s mismatches %.2f%%)\n", suggested, 100*fit, rule, 100*rate/float64(phrases))
+		}
+		return nil
+	},
+}
+
+func init() {
+	verifyEncoderCmd.Flags().IntVarP(&verifyExamples, "examples", "n", 5, "Number of mismatches to show for each word length")
+	rootCmd.AddCommand(verifyEncoderCmd)
+}
@@ -0,0 +1,160 @@
+package dict
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// backupTimeFormat is the layout of backup IDs. IDs sort in time order.
+const backupTimeFormat = "20060102-150405.000"
+
+// Backup is a copy of a dictionary file taken before it was overwritten.
+type Backup struct {
+	ID   string    // Identifies the backup for RestoreBackup
+	Path string    // Path of the backup file
+	Time time.Time // When the backup was taken
+	Size int64
+}
+
+// BackupDir returns the directory holding the backups of the dictionary
+// file at path.
+func BackupDir(path string) string {
+	return filepath.Join(filepath.Dir(path), ".rime-dict-manager", "backups")
+}
+
+func backupPrefix(path string) string {
+	return filepath.Base(path) + "."
+}
+
+// ListBackups returns the backups of the dictionary file at path, newest
+// first.
+func ListBackups(path string) ([]Backup, error) {
+	dir := BackupDir(path)
+	files, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	prefix := backupPrefix(path)
+	var backups []Backup
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ".bak") {
+			continue
+		}
+		id := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".bak")
+		if len(id) < len(backupTimeFormat) {
+			continue
+		}
+		t, err := time.ParseInLocation(backupTimeFormat, id[:len(backupTimeFormat)], time.Local)
+		if err != nil {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, Backup{ID: id, Path: filepath.Join(dir, name), Time: t, Size: info.Size()})
+	}
+
+	sort.Slice(backups, func(i, j int) bool { return backups[i].ID > backups[j].ID })
+	return backups, nil
+}
+
+// backupFile copies the file at path into its backup directory and
+// removes all but the newest keep backups. A missing file is not an error.
+func backupFile(path string, keep int) error {
+	src, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dir := BackupDir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	id := time.Now().Format(backupTimeFormat)
+	backupPath := filepath.Join(dir, backupPrefix(path)+id+".bak")
+	for i := 1; ; i++ {
+		if _, err := os.Stat(backupPath); os.IsNotExist(err) {
+			break
+		}
+		backupPath = filepath.Join(dir, fmt.Sprintf("%s%s-%d.bak", backupPrefix(path), id, i))
+	}
+
+	err = writeFileAtomic(backupPath, func(w io.Writer) error {
+		_, err := io.Copy(w, src)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	backups, err := ListBackups(path)
+	if err != nil {
+		return err
+	}
+	for _, b := range backups[min(keep, len(backups)):] {
+		if err := os.Remove(b.Path); err != nil {
+			return fmt.Errorf("failed to remove old backup: %w", err)
+		}
+	}
+	return nil
+}
+
+// RestoreBackup replaces the dictionary file at path with the backup
+// identified by id, which may be a unique prefix of a backup ID. The
+// current file is backed up first, keeping at most keep backups, so a
+// restore can itself be undone.
+func RestoreBackup(path, id string, keep int) (Backup, error) {
+	backups, err := ListBackups(path)
+	if err != nil {
+		return Backup{}, err
+	}
+
+	var matches []Backup
+	for _, b := range backups {
+		if b.ID == id {
+			matches = []Backup{b}
+			break
+		}
+		if strings.HasPrefix(b.ID, id) {
+			matches = append(matches, b)
+		}
+	}
+	switch {
+	case id == "" || len(matches) == 0:
+		return Backup{}, fmt.Errorf("backup '%s' not found for %s", id, path)
+	case len(matches) > 1:
+		return Backup{}, fmt.Errorf("backup id '%s' is ambiguous: it matches %d backups", id, len(matches))
+	}
+	backup := matches[0]
+
+	content, err := os.ReadFile(backup.Path)
+	if err != nil {
+		return Backup{}, fmt.Errorf("failed to read backup: %w", err)
+	}
+	if keep > 0 {
+		if err := backupFile(path, keep); err != nil {
+			return Backup{}, fmt.Errorf("failed to back up dictionary: %w", err)
+		}
+	}
+	err = writeFileAtomic(path, func(w io.Writer) error {
+		_, err := w.Write(content)
+		return err
+	})
+	return backup, err
+}
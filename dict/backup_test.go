@@ -0,0 +1,103 @@
+package dict
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDictionary_SaveKeepsBackups(t *testing.T) {
+	dictPath := createTempDictFile(t, t.TempDir(), "---\n...\nword\tcode\t0\n")
+
+	for i := 1; i <= 4; i++ {
+		d := NewDictionary(dictPath)
+		d.Backups = 2
+		if err := d.Load(); err != nil {
+			t.Fatalf("Load() failed: %v", err)
+		}
+		d.AddOrUpdate("word", "code", AbsoluteWeight(i), "")
+		if err := d.Save(); err != nil {
+			t.Fatalf("Save() failed: %v", err)
+		}
+	}
+
+	backups, err := ListBackups(dictPath)
+	if err != nil {
+		t.Fatalf("ListBackups() failed: %v", err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("Expected 2 backups, got %d", len(backups))
+	}
+	// Newest first: the content before the last two saves.
+	if got := readFile(t, backups[0].Path); got != "---\n...\nword\tcode\t3\n" {
+		t.Errorf("Unexpected newest backup content: %q", got)
+	}
+	if got := readFile(t, backups[1].Path); got != "---\n...\nword\tcode\t2\n" {
+		t.Errorf("Unexpected oldest backup content: %q", got)
+	}
+
+	restored, err := RestoreBackup(dictPath, backups[1].ID, 2)
+	if err != nil {
+		t.Fatalf("RestoreBackup() failed: %v", err)
+	}
+	if restored.ID != backups[1].ID {
+		t.Errorf("Restored backup %s, want %s", restored.ID, backups[1].ID)
+	}
+	if got := readFile(t, dictPath); got != "---\n...\nword\tcode\t2\n" {
+		t.Errorf("Unexpected content after restore: %q", got)
+	}
+
+	// The restore backed up the content it replaced.
+	backups, _ = ListBackups(dictPath)
+	if len(backups) != 2 || readFile(t, backups[0].Path) != "---\n...\nword\tcode\t4\n" {
+		t.Errorf("Restore should back up the replaced content, backups: %+v", backups)
+	}
+}
+
+func TestRestoreBackup_NotFound(t *testing.T) {
+	dictPath := createTempDictFile(t, t.TempDir(), "---\n...\n")
+	if _, err := RestoreBackup(dictPath, "20000101", 5); err == nil {
+		t.Error("Expected an error for an unknown backup id")
+	}
+}
+
+func TestDictionary_SaveAtomic(t *testing.T) {
+	dir := t.TempDir()
+	target := createTempDictFile(t, dir, "---\n...\nword\tcode\t1\n")
+	if err := os.Chmod(target, 0o600); err != nil {
+		t.Fatalf("Chmod failed: %v", err)
+	}
+	link := filepath.Join(dir, "link.dict.yaml")
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("Symlinks are not supported: %v", err)
+	}
+
+	d := NewDictionary(link)
+	if err := d.Load(); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	d.AddOrUpdate("word", "code", AbsoluteWeight(2), "")
+	if err := d.Save(); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("Save() replaced the symlink with a regular file")
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected mode 0600 to be kept, got %v", info.Mode().Perm())
+	}
+	if got := readFile(t, target); got != "---\n...\nword\tcode\t2\n" {
+		t.Errorf("Unexpected content: %q", got)
+	}
+
+	// No temporary files are left behind.
+	files, _ := os.ReadDir(dir)
+	if len(files) != 2 {
+		t.Errorf("Expected only the dictionary and the link, got %d files", len(files))
+	}
+}
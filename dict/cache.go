@@ -0,0 +1,202 @@
+package dict
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// cacheFormat is bumped whenever the cached data changes shape, so that
+// files written by older versions are rebuilt instead of misread.
+const cacheFormat = 1
+
+// Cache keeps parsed dictionaries in binary files, so that large main
+// dictionaries need not be parsed again on every run. Each cached item
+// records the path, size, modification time and hash of every file it was
+// built from. A file whose size and modification time are unchanged is
+// trusted without reading it; otherwise its content is hashed, and the
+// item is rebuilt if that changed too. A Cache with an empty Dir caches
+// nothing.
+type Cache struct {
+	Dir string
+}
+
+// DefaultCacheDir returns the rime-dict-manager directory in the user's
+// cache directory.
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "rime-dict-manager"), nil
+}
+
+type cacheRecord struct {
+	Format  int
+	Sources []Fingerprint
+	Payload []byte // The gob encoded item
+}
+
+// cachedView is the part of a MergedView that is cached. Entries only
+// keep the fields code generation and lookups need.
+type cachedView struct {
+	Sources []string
+	Entries []cachedEntry
+}
+
+type cachedEntry struct {
+	Word, Code string
+	Weight     Weight
+	Source     int // Index into Sources
+	Group      string
+}
+
+// LoadMerged is like the package level LoadMerged, but returns the view
+// from the cache if none of the files changed. A view from the cache has
+// no Root, and its entries only carry their word, code and weight.
+func (c *Cache) LoadMerged(rimeDir string, paths ...string) (*MergedView, error) {
+	key := c.key("view", rimeDir, paths)
+	var cached cachedView
+	if c.load(key, &cached) {
+		return &MergedView{Sources: cached.Sources, Entries: cached.sourcedEntries()}, nil
+	}
+
+	v, err := LoadMerged(rimeDir, paths...)
+	if err != nil {
+		return nil, err
+	}
+	// A cache that cannot be written only costs time on the next run.
+	_ = c.store(key, v.fingerprints, newCachedView(v.Sources, v.Entries))
+	return v, nil
+}
+
+// LoadCodeIndex returns the code index of the dictionaries at paths and
+// the tables they import. It is built from the view LoadMerged caches,
+// so a view and its index share one cache file.
+func (c *Cache) LoadCodeIndex(rimeDir string, paths ...string) (*CodeIndex, error) {
+	v, err := c.LoadMerged(rimeDir, paths...)
+	if err != nil {
+		return nil, err
+	}
+	return NewCodeIndex(v), nil
+}
+
+// newCachedView packs entries read from sources for the cache.
+func newCachedView(sources []string, entries []SourcedEntry) cachedView {
+	cached := cachedView{Sources: sources}
+	index := make(map[string]int)
+	for i, source := range sources {
+		index[source] = i
+	}
+	for _, e := range entries {
+		cached.Entries = append(cached.Entries, cachedEntry{
+			Word: e.Entry.Word, Code: e.Entry.Code, Weight: e.Entry.Weight,
+			Source: index[e.Source], Group: e.Group,
+		})
+	}
+	return cached
+}
+
+// sourcedEntries unpacks the cached entries.
+func (cached cachedView) sourcedEntries() []SourcedEntry {
+	entries := make([]SourcedEntry, 0, len(cached.Entries))
+	for _, e := range cached.Entries {
+		entries = append(entries, SourcedEntry{
+			Entry:  Entry{Word: e.Word, Code: e.Code, Weight: e.Weight},
+			Source: cached.Sources[e.Source],
+			Group:  e.Group,
+		})
+	}
+	return entries
+}
+
+// key names the cache file of an item built from paths.
+func (c *Cache) key(kind, rimeDir string, paths []string) string {
+	parts := []string{kind, rimeDir}
+	for _, path := range paths {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		parts = append(parts, path)
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return kind + "-" + hex.EncodeToString(sum[:8]) + ".gob"
+}
+
+// load decodes the cached item key into v. It reports false if there is
+// no such item, it cannot be read, or a file it was built from changed.
+func (c *Cache) load(key string, v any) bool {
+	if c == nil || c.Dir == "" {
+		return false
+	}
+	content, err := os.ReadFile(filepath.Join(c.Dir, key))
+	if err != nil {
+		return false
+	}
+	var record cacheRecord
+	if err := gob.NewDecoder(bytes.NewReader(content)).Decode(&record); err != nil || record.Format != cacheFormat {
+		return false
+	}
+	touched := false
+	for i, source := range record.Sources {
+		current, ok := unchangedSince(source)
+		if !ok {
+			return false
+		}
+		if !current.ModTime.Equal(source.ModTime) {
+			record.Sources[i], touched = current, true
+		}
+	}
+	if gob.NewDecoder(bytes.NewReader(record.Payload)).Decode(v) != nil {
+		return false
+	}
+	if touched {
+		// Record the new modification times, so that the files need not
+		// be hashed again on the next run.
+		_ = c.write(key, record)
+	}
+	return true
+}
+
+// store saves v as the cached item key, built from the files in sources.
+func (c *Cache) store(key string, sources []Fingerprint, v any) error {
+	if c == nil || c.Dir == "" {
+		return nil
+	}
+	var payload bytes.Buffer
+	if err := gob.NewEncoder(&payload).Encode(v); err != nil {
+		return fmt.Errorf("failed to encode cache item: %w", err)
+	}
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return c.write(key, cacheRecord{Format: cacheFormat, Sources: sources, Payload: payload.Bytes()})
+}
+
+func (c *Cache) write(key string, record cacheRecord) error {
+	return writeFileAtomic(filepath.Join(c.Dir, key), func(w io.Writer) error {
+		return gob.NewEncoder(w).Encode(record)
+	})
+}
+
+// unchangedSince reports whether the file of fp still has the same
+// content, and returns its current fingerprint. A file with the same size
+// and modification time is trusted without reading it; one with only a
+// new modification time, e.g. from touch, is hashed.
+func unchangedSince(fp Fingerprint) (Fingerprint, bool) {
+	info, err := os.Stat(fp.Path)
+	if err != nil || info.Size() != fp.Size {
+		return fp, false
+	}
+	if info.ModTime().Equal(fp.ModTime) {
+		return fp, true
+	}
+	current, err := FingerprintFile(fp.Path)
+	return current, err == nil && fp.SameContent(current)
+}
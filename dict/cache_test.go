@@ -0,0 +1,107 @@
+package dict
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCache_LoadMerged(t *testing.T) {
+	dir := t.TempDir()
+	writeDictFiles(t, dir, map[string]string{
+		"main.dict.yaml":  "---\nname: main\nimport_tables:\n  - extra\n...\n中\tkhk\t10\n",
+		"extra.dict.yaml": "---\nname: extra\n...\n## Work\n国\tlgyi\n",
+	})
+	mainPath := filepath.Join(dir, "main.dict.yaml")
+	extraPath := filepath.Join(dir, "extra.dict.yaml")
+	cache := &Cache{Dir: filepath.Join(dir, "cache")}
+
+	v, err := cache.LoadMerged(dir, mainPath)
+	if err != nil {
+		t.Fatalf("LoadMerged() failed: %v", err)
+	}
+	if v.Root == nil {
+		t.Fatal("The first load should parse the files")
+	}
+	files, _ := os.ReadDir(cache.Dir)
+	if len(files) != 1 {
+		t.Fatalf("Expected one cache file, got %d", len(files))
+	}
+
+	v, err = cache.LoadMerged(dir, mainPath)
+	if err != nil {
+		t.Fatalf("LoadMerged() failed: %v", err)
+	}
+	if v.Root != nil {
+		t.Error("The second load should come from the cache")
+	}
+	if len(v.Sources) != 2 || len(v.Entries) != 2 {
+		t.Fatalf("Cached view has sources %v and %d entries", v.Sources, len(v.Entries))
+	}
+	got := v.Entries[1]
+	if got.Entry.Word != "国" || got.Entry.Code != "lgyi" || got.Source != extraPath || got.Group != "Work" {
+		t.Errorf("Unexpected cached entry %+v", got)
+	}
+	if v.Entries[0].Entry.Weight != AbsoluteWeight(10) {
+		t.Errorf("Expected the cached weight 10, got %v", v.Entries[0].Entry.Weight)
+	}
+
+	// Changing an imported table invalidates the cache.
+	os.WriteFile(extraPath, []byte("---\nname: extra\n...\n国\tlgyi\n人\twwww\n"), 0o644)
+	v, err = cache.LoadMerged(dir, mainPath)
+	if err != nil {
+		t.Fatalf("LoadMerged() failed: %v", err)
+	}
+	if v.Root == nil || len(v.Entries) != 3 {
+		t.Errorf("A changed import should be parsed again, got %d entries", len(v.Entries))
+	}
+
+	if v, _ = cache.LoadMerged(dir, mainPath); v.Root != nil {
+		t.Error("The rebuilt cache should be used")
+	}
+
+	// A new modification time alone does not, as the content is the same.
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(mainPath, later, later)
+	if v, _ = cache.LoadMerged(dir, mainPath); v.Root != nil {
+		t.Error("A touched file with the same content should not invalidate the cache")
+	}
+
+	// Once the new time is recorded, a file of the same size and time is
+	// trusted without reading it.
+	os.WriteFile(mainPath, []byte("---\nname: main\nimport_tables:\n  - extra\n...\n中\tkhk\t20\n"), 0o644)
+	os.Chtimes(mainPath, later, later)
+	if v, _ = cache.LoadMerged(dir, mainPath); v.Root != nil {
+		t.Error("A file with the recorded size and time should not be hashed")
+	}
+
+	// A new time and content are noticed.
+	os.Chtimes(mainPath, later.Add(time.Hour), later.Add(time.Hour))
+	if v, _ = cache.LoadMerged(dir, mainPath); v.Root == nil || v.Entries[0].Entry.Weight != AbsoluteWeight(20) {
+		t.Error("A changed file should be parsed again")
+	}
+}
+
+func TestCache_Unusable(t *testing.T) {
+	dir := t.TempDir()
+	writeDictFiles(t, dir, map[string]string{"main.dict.yaml": "中\tkhk\n"})
+	mainPath := filepath.Join(dir, "main.dict.yaml")
+
+	// Without a directory nothing is cached.
+	if v, err := (&Cache{}).LoadMerged(dir, mainPath); err != nil || v.Root == nil {
+		t.Errorf("LoadMerged() without a cache = %v, %v", v, err)
+	}
+
+	// A corrupt cache file is ignored and replaced.
+	cache := &Cache{Dir: filepath.Join(dir, "cache")}
+	cache.LoadMerged(dir, mainPath)
+	files, _ := os.ReadDir(cache.Dir)
+	os.WriteFile(filepath.Join(cache.Dir, files[0].Name()), []byte("garbage"), 0o644)
+	if v, err := cache.LoadMerged(dir, mainPath); err != nil || v.Root == nil || len(v.Entries) != 1 {
+		t.Errorf("LoadMerged() with a corrupt cache = %v, %v", v, err)
+	}
+	if v, _ := cache.LoadMerged(dir, mainPath); v.Root != nil {
+		t.Error("The corrupt cache file should have been replaced")
+	}
+}
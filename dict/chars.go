@@ -0,0 +1,75 @@
+package dict
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// CollectCharCodes collects the codes of the single characters in views, in
+// file order. A character listed in an earlier view takes its codes from
+// that view only, so overrides come first.
+func CollectCharCodes(views ...*MergedView) map[rune][]string {
+	codes := make(map[rune][]string)
+	for _, v := range views {
+		found := make(map[rune][]string)
+		for _, se := range v.Entries {
+			char := []rune(se.Entry.Word)
+			if len(char) != 1 || se.Entry.Code == "" {
+				continue
+			}
+			if _, ok := codes[char[0]]; ok {
+				continue
+			}
+			found[char[0]] = append(found[char[0]], se.Entry.Code)
+		}
+		for r, c := range found {
+			codes[r] = c
+		}
+	}
+	return codes
+}
+
+// charOverrider is implemented by encoders whose character codes can be
+// replaced.
+type charOverrider interface {
+	overrideChar(r rune, codes []string)
+}
+
+// overrideChars replaces the codes the encoder has for each character of
+// chars.
+func overrideChars(enc Encoder, chars map[rune][]string) error {
+	if len(chars) == 0 {
+		return nil
+	}
+	o, ok := enc.(charOverrider)
+	if !ok {
+		return fmt.Errorf("the encoder does not support character overrides")
+	}
+	for r, codes := range chars {
+		o.overrideChar(r, codes)
+	}
+	return nil
+}
+
+func (e *TableEncoder) overrideChar(r rune, codes []string) {
+	e.charMap[r] = nil
+	for _, code := range codes {
+		e.addCode(r, code)
+	}
+}
+
+func (e *PinyinEncoder) overrideChar(r rune, codes []string) {
+	e.readings[r] = nil
+	for _, code := range codes {
+		if code = strings.Join(strings.Fields(code), " "); !slices.Contains(e.readings[r], code) {
+			e.readings[r] = append(e.readings[r], code)
+		}
+	}
+}
+
+func (e *ShuangpinEncoder) overrideChar(r rune, codes []string) {
+	if o, ok := e.Pinyin.(charOverrider); ok {
+		o.overrideChar(r, codes)
+	}
+}
@@ -0,0 +1,51 @@
+package dict
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCollectCharCodes(t *testing.T) {
+	dir := t.TempDir()
+	writeDictFiles(t, dir, map[string]string{
+		"chars.dict.yaml": "---\nname: chars\n...\n国\tlgyy\n",
+		"extra.dict.yaml": "---\nname: extra\n...\n国\tlgyi\n丼\tfjfj\n丼\tfj\n国家\tlgpe\n",
+	})
+	overrides, _ := LoadMerged(dir, filepath.Join(dir, "chars.dict.yaml"))
+	extra, _ := LoadMerged(dir, filepath.Join(dir, "extra.dict.yaml"))
+
+	codes := CollectCharCodes(overrides, extra)
+	expected := map[rune][]string{'国': {"lgyy"}, '丼': {"fjfj", "fj"}}
+	if !reflect.DeepEqual(codes, expected) {
+		t.Errorf("CollectCharCodes = %v, want %v", codes, expected)
+	}
+}
+
+func TestNewEncoder_Chars(t *testing.T) {
+	view, err := LoadMerged("", wubiSampleDict)
+	if err != nil {
+		t.Fatalf("LoadMerged failed: %v", err)
+	}
+	chars := map[rune][]string{'丼': {"fjfj"}, '国': {"lgyy", "l"}}
+
+	encoder, err := NewEncoder("wubi86", EncoderOptions{Main: view, Chars: chars})
+	if err != nil {
+		t.Fatalf("NewEncoder failed: %v", err)
+	}
+	if code, err := encoder.GenerateCode("中丼"); err != nil || code != "khfj" {
+		t.Errorf("GenerateCode(中丼) = %s, %v; want the added character's code khfj", code, err)
+	}
+	if code, err := encoder.GenerateCode("国"); err != nil || code != "lgyy" {
+		t.Errorf("GenerateCode(国) = %s, %v; want the forced code lgyy", code, err)
+	}
+
+	pinyinView, _ := LoadMerged("", pinyinSampleDict)
+	encoder, err = NewEncoder("shuangpin-xiaohe", EncoderOptions{Main: pinyinView, Chars: map[rune][]string{'重': {"zhong"}}})
+	if err != nil {
+		t.Fatalf("NewEncoder failed: %v", err)
+	}
+	if code, err := encoder.GenerateCode("重要"); err != nil || code != "vs yc" {
+		t.Errorf("GenerateCode(重要) = %s, %v; want the overridden reading", code, err)
+	}
+}
@@ -2,9 +2,12 @@ package dict
 
 import (
 	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"os"
-	"strconv"
+	"slices"
 	"strings"
 )
 
@@ -12,27 +15,76 @@ import (
 type Entry struct {
 	Word      string
 	Code      string
-	Weight    int
-	Comment   string // For standalone comment lines
-	Group     string // For group lines like '## GroupName'
-	IsComment bool   // True if the line is a comment
-	IsGroup   bool   // True if the line is a group header
-	RawLine   string // The original, unmodified line
+	Stem      string // Optional stem column used by some table schemas
+	Weight    Weight
+	Comment   string   // For standalone comment lines
+	Group     string   // For group lines like '## GroupName'
+	IsComment bool     // True if the line is a comment
+	IsGroup   bool     // True if the line is a group header
+	Extra     []string // Fields beyond the declared columns, kept as they are
+	RawLine   string   // The original, unmodified line
+	Dirty     bool     // True if the entry changed since it was loaded
+
+	// eol is the line's own terminator when it differs from the file's
+	// usual one, so that an untouched line is written back unchanged.
+	eol string
 }
 
 // Dictionary holds the entire content of a dictionary file.
 type Dictionary struct {
 	Header  []string // YAML header part
+	Meta    Metadata // Well-known header fields, written back to Header on Save
 	Entries []Entry
-	path    string
+	// Warnings lists the problems Load found that did not stop it, such
+	// as weights that are not numbers.
+	Warnings []string
+	Backups  int // Number of backups to keep when saving; 0 disables backups
+	path     string
+	lock     *FileLock
+	loaded   *Fingerprint // The file as it was loaded, to detect external edits
+
+	// noFinalNewline records that the file did not end with a newline, so
+	// Save can leave it out as well.
+	noFinalNewline bool
+	// hasBOM and lineEnding record the byte order mark and the line
+	// ending style of the file so Save can keep them.
+	hasBOM     bool
+	lineEnding string
+	// headerEOL holds the terminator of each Header line that differs
+	// from lineEnding, or "" for the others; nil if none differs.
+	headerEOL []string
+	// parsedColumns is the column layout the entries were read with. If
+	// Meta.Columns no longer matches it, WriteTo renders every word entry
+	// again instead of writing back its RawLine.
+	parsedColumns []string
 }
 
+// DefaultColumns is the column order Rime assumes when a dictionary does
+// not declare `columns` in its header.
+var DefaultColumns = []string{"text", "code", "weight"}
+
 // NewDictionary creates a new Dictionary instance.
 func NewDictionary(path string) *Dictionary {
 	return &Dictionary{path: path}
 }
 
-// Load reads and parses the dictionary file.
+// Parse reads a dictionary from r. The result has no file path, so it
+// can be written with WriteTo but not with Save.
+func Parse(r io.Reader) (*Dictionary, error) {
+	d := &Dictionary{}
+	if err := d.parse(r); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Path returns the file the dictionary is loaded from and saved to.
+func (d *Dictionary) Path() string {
+	return d.path
+}
+
+// Load reads and parses the dictionary file. The file's fingerprint is
+// recorded so that Save can detect changes made by other programs.
 func (d *Dictionary) Load() error {
 	file, err := os.Open(d.path)
 	if err != nil {
@@ -40,194 +92,447 @@ func (d *Dictionary) Load() error {
 	}
 	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
-	inHeader := true
-	for scanner.Scan() {
-		line := scanner.Text()
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to open dictionary file: %w", err)
+	}
+	h := sha256.New()
+	if err := d.parse(io.TeeReader(file, h)); err != nil {
+		return err
+	}
+	// Hash whatever parse did not need to read.
+	if _, err := io.Copy(h, file); err != nil {
+		return fmt.Errorf("error reading dictionary file: %w", err)
+	}
+	d.loaded = &Fingerprint{Path: d.path, Size: info.Size(), ModTime: info.ModTime(), Hash: hex.EncodeToString(h.Sum(nil))}
 
-		if inHeader {
-			if strings.HasPrefix(line, "...") {
-				inHeader = false
-			}
-			d.Header = append(d.Header, line)
-			continue
+	return nil
+}
+
+// parse replaces the content of d with the dictionary read from r.
+//
+// The YAML header is optional: it starts with a '---' line or directly
+// with a 'key:' line, which may only be preceded by comments and blank
+// lines, and must end with a '...' line. A file whose first entry comes
+// before any header line has no header. Lines may end with "\n" or
+// "\r\n"; the more common style is used for new and changed lines, and
+// every other line keeps its own.
+func (d *Dictionary) parse(r io.Reader) error {
+	d.Header, d.Meta, d.Entries, d.Warnings = nil, Metadata{}, nil, nil
+	d.headerEOL = nil
+	d.parsedColumns = DefaultColumns
+	d.noFinalNewline = false
+	d.lineEnding = "\n"
+
+	const (
+		inPreamble = iota // comments and blank lines before the header
+		inHeader
+		inBody
+	)
+
+	reader := newLineReader(r)
+	state := inPreamble
+	var preamble, preambleEOL, headerEOL []string
+	columns := DefaultColumns
+	lineNo, headerStart := 0, 0
+	crlf, lf := 0, 0
+
+	addEntry := func(line string, lineNo int, eol string) {
+		entry := parseEntry(line, columns)
+		entry.eol = eol
+		if entry.Weight.Kind == WeightInvalid {
+			d.Warnings = append(d.Warnings, fmt.Sprintf("line %d: invalid weight '%s', kept as it is", lineNo, entry.Weight.Raw))
+		}
+		d.Entries = append(d.Entries, entry)
+	}
+
+	for {
+		line, eol, err := reader.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("error reading dictionary file: %w", err)
+		}
+		lineNo++
+		d.noFinalNewline = eol == ""
+		switch eol {
+		case "\r\n":
+			crlf++
+		case "\n":
+			lf++
 		}
 
-		var entry Entry
-		entry.RawLine = line
-
-		if strings.HasPrefix(line, "##") {
-			entry.IsGroup = true
-			entry.Group = strings.TrimSpace(strings.TrimPrefix(line, "##"))
-		} else if strings.HasPrefix(line, "#") {
-			entry.IsComment = true
-			entry.Comment = line
-		} else if strings.TrimSpace(line) != "" {
-			parts := strings.Split(line, "\t")
-			if len(parts) >= 2 {
-				entry.Word = parts[0]
-				entry.Code = parts[1]
-				if len(parts) > 2 {
-					weight, err := strconv.Atoi(parts[2])
-					if err == nil {
-						entry.Weight = weight
-					}
+		switch state {
+		case inPreamble:
+			if strings.HasPrefix(line, "---") || isHeaderKey(line) {
+				d.Header = append(preamble, line)
+				headerEOL = append(preambleEOL, eol)
+				preamble, preambleEOL = nil, nil
+				headerStart = lineNo
+				state = inHeader
+				continue
+			}
+			if strings.HasPrefix(line, "#") || strings.TrimSpace(line) == "" {
+				preamble = append(preamble, line)
+				preambleEOL = append(preambleEOL, eol)
+				continue
+			}
+			// An entry before any header line: the file has no header.
+			for i, l := range preamble {
+				addEntry(l, i+1, preambleEOL[i])
+			}
+			preamble, preambleEOL = nil, nil
+			state = inBody
+			addEntry(line, lineNo, eol)
+		case inHeader:
+			d.Header = append(d.Header, line)
+			headerEOL = append(headerEOL, eol)
+			if strings.HasPrefix(line, "...") {
+				state = inBody
+				meta, err := parseMetadata(d.Header)
+				if err != nil {
+					return err
+				}
+				d.Meta = meta
+				if columns, err = d.columns(); err != nil {
+					return err
 				}
+				d.parsedColumns = columns
 			}
+		case inBody:
+			addEntry(line, lineNo, eol)
 		}
-		d.Entries = append(d.Entries, entry)
 	}
 
-	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("error reading dictionary file: %w", err)
+	if state == inHeader {
+		return fmt.Errorf("line %d: dictionary header is not terminated by a '...' line", headerStart)
+	}
+	for i, l := range preamble {
+		addEntry(l, i+1, preambleEOL[i])
+	}
+	d.hasBOM = reader.hasBOM
+	if crlf > lf {
+		d.lineEnding = "\r\n"
+	}
+
+	// Only remember the terminators that differ from the usual one. The
+	// last line's is covered by noFinalNewline.
+	for i := range d.Entries {
+		if d.Entries[i].eol == d.lineEnding || d.Entries[i].eol == "" {
+			d.Entries[i].eol = ""
+		}
+	}
+	if crlf > 0 && lf > 0 {
+		for i, eol := range headerEOL {
+			if eol == d.lineEnding {
+				headerEOL[i] = ""
+			}
+		}
+		d.headerEOL = headerEOL
 	}
 
 	return nil
 }
 
-// Save writes the dictionary content back to the file.
+// isHeaderKey reports whether a line before the body is a YAML key, which
+// starts a header that has no '---' line. Entries are told apart by their
+// tabs.
+func isHeaderKey(line string) bool {
+	return !strings.Contains(line, "\t") && headerKeyRe.MatchString(line)
+}
+
+// columns returns the validated column layout declared in the header.
+func (d *Dictionary) columns() ([]string, error) {
+	if len(d.Meta.Columns) == 0 {
+		return DefaultColumns, nil
+	}
+	seen := make(map[string]bool)
+	for _, column := range d.Meta.Columns {
+		switch column {
+		case "text", "code", "stem", "weight":
+		default:
+			return nil, fmt.Errorf("unsupported dictionary column '%s'", column)
+		}
+		if seen[column] {
+			return nil, fmt.Errorf("dictionary column '%s' is declared twice", column)
+		}
+		seen[column] = true
+	}
+	if !seen["text"] {
+		return nil, fmt.Errorf("dictionary columns must include 'text'")
+	}
+	return d.Meta.Columns, nil
+}
+
+// parseEntry parses a line of the dictionary body. Fields are assigned by
+// position according to columns; missing trailing fields are left empty.
+// A weight that does not parse is kept as an invalid weight.
+func parseEntry(line string, columns []string) Entry {
+	var entry Entry
+	entry.RawLine = line
+
+	if strings.HasPrefix(line, "##") {
+		entry.IsGroup = true
+		entry.Group = strings.TrimSpace(strings.TrimPrefix(line, "##"))
+	} else if strings.HasPrefix(line, "#") {
+		entry.IsComment = true
+		entry.Comment = line
+	} else if strings.TrimSpace(line) != "" {
+		parts := strings.Split(line, "\t")
+		for i, part := range parts {
+			if i >= len(columns) {
+				entry.Extra = append(entry.Extra, parts[i:]...)
+				break
+			}
+			switch columns[i] {
+			case "text":
+				entry.Word = part
+			case "code":
+				entry.Code = part
+			case "stem":
+				entry.Stem = part
+			case "weight":
+				weight, err := ParseWeight(part)
+				if err != nil {
+					weight = InvalidWeight(part)
+				}
+				entry.Weight = weight
+			}
+		}
+	}
+	return entry
+}
+
+// formatEntry renders a word entry according to columns. Trailing empty
+// fields are left out unless the entry has extra fields after them.
+func formatEntry(entry Entry, columns []string) string {
+	fields := make([]string, len(columns))
+	for i, column := range columns {
+		switch column {
+		case "text":
+			fields[i] = entry.Word
+		case "code":
+			fields[i] = entry.Code
+		case "stem":
+			fields[i] = entry.Stem
+		case "weight":
+			fields[i] = entry.Weight.String()
+		}
+	}
+	for len(entry.Extra) == 0 && len(fields) > 1 && fields[len(fields)-1] == "" {
+		fields = fields[:len(fields)-1]
+	}
+	fields = append(fields, entry.Extra...)
+	return strings.Join(fields, "\t")
+}
+
+// Save writes the dictionary content back to the file. The file is
+// replaced atomically, after a backup of the previous content is taken if
+// Backups is set. If the file was loaded and has since been changed by
+// another program, Save returns ErrModifiedExternally and writes nothing.
 func (d *Dictionary) Save() error {
-	file, err := os.Create(d.path)
+	if d.path == "" {
+		return fmt.Errorf("dictionary has no file path to save to")
+	}
+	if _, err := d.columns(); err != nil {
+		return err
+	}
+	if err := d.checkUnchanged(); err != nil {
+		return err
+	}
+
+	if d.Backups > 0 {
+		if err := backupFile(d.path, d.Backups); err != nil {
+			return fmt.Errorf("failed to back up dictionary: %w", err)
+		}
+	}
+
+	err := writeFileAtomic(d.path, func(w io.Writer) error {
+		_, err := d.WriteTo(w)
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("failed to create dictionary file for writing: %w", err)
+		return err
 	}
-	defer file.Close()
 
-	writer := bufio.NewWriter(file)
+	d.loaded = nil
+	if fp, err := FingerprintFile(d.path); err == nil {
+		d.loaded = &fp
+	}
+	return nil
+}
 
-	for _, line := range d.Header {
-		_, _ = writer.WriteString(line + "\n")
+// WriteTo writes the dictionary content to w. Entries that are not Dirty
+// are written back exactly as they were read, unless the columns changed
+// since, in which case every word entry is rendered in the new order.
+func (d *Dictionary) WriteTo(w io.Writer) (int64, error) {
+	columns, err := d.columns()
+	if err != nil {
+		return 0, err
 	}
+	reorder := !slices.Equal(columns, d.parsedColumns)
+
+	header := applyMetadata(d.Header, d.Meta)
+	d.headerEOL = alignLineEndings(d.Header, d.headerEOL, header)
+	d.Header = header
+	lines := make([]string, 0, len(d.Header)+len(d.Entries))
+	lines = append(lines, d.Header...)
+	eols := make([]string, len(d.Header), cap(lines))
+	copy(eols, d.headerEOL)
 
 	for _, entry := range d.Entries {
-		if entry.IsGroup {
-			_, _ = writer.WriteString(fmt.Sprintf("## %s\n", entry.Group))
+		if entry.Dirty {
+			eols = append(eols, "")
+		} else {
+			eols = append(eols, entry.eol)
+		}
+		if reorder && entry.Word != "" && !entry.IsGroup && !entry.IsComment {
+			lines = append(lines, formatEntry(entry, columns))
+		} else if !entry.Dirty && entry.RawLine != "" {
+			// Untouched lines are written back byte-for-byte.
+			lines = append(lines, entry.RawLine)
+		} else if entry.IsGroup {
+			lines = append(lines, fmt.Sprintf("## %s", entry.Group))
 		} else if entry.IsComment {
-			_, _ = writer.WriteString(entry.Comment + "\n")
+			lines = append(lines, entry.Comment)
 		} else if entry.Word != "" {
-			_, _ = writer.WriteString(fmt.Sprintf("%s	%s	%d\n", entry.Word, entry.Code, entry.Weight))
+			lines = append(lines, formatEntry(entry, columns))
 		} else {
-			_, _ = writer.WriteString(entry.RawLine + "\n")
+			lines = append(lines, entry.RawLine)
+		}
+	}
+
+	lineEnding := d.lineEnding
+	if lineEnding == "" {
+		lineEnding = "\n"
+	}
+
+	writer := bufio.NewWriter(w)
+	var n int64
+	if d.hasBOM {
+		written, err := writer.WriteString(utf8BOM)
+		n += int64(written)
+		if err != nil {
+			return n, err
+		}
+	}
+	for i, line := range lines {
+		switch {
+		case i == len(lines)-1 && d.noFinalNewline:
+		case eols[i] != "":
+			line += eols[i]
+		default:
+			line += lineEnding
+		}
+		written, err := writer.WriteString(line)
+		n += int64(written)
+		if err != nil {
+			return n, err
 		}
 	}
 
-	return writer.Flush()
+	return n, writer.Flush()
+}
+
+// alignLineEndings carries the line endings of an old header over to the
+// lines of its rewritten version that are still there, matching them in
+// order. New lines get "", the usual ending.
+func alignLineEndings(old, oldEOL, header []string) []string {
+	if oldEOL == nil {
+		return nil
+	}
+	eols := make([]string, len(header))
+	j := 0
+	for i, line := range header {
+		for k := j; k < len(old); k++ {
+			if old[k] == line {
+				eols[i], j = oldEOL[k], k+1
+				break
+			}
+		}
+	}
+	return eols
 }
 
 // AddOrUpdate finds a word and updates it, or adds it if it doesn't exist.
-func (d *Dictionary) AddOrUpdate(word, code string, weight int, group string) {
+// group is only used for a new word; call MoveToGroup to move an existing
+// one.
+func (d *Dictionary) AddOrUpdate(word, code string, weight Weight, group string) {
 	// First, try to update existing entry
 	for i := range d.Entries {
 		if d.Entries[i].Word == word {
 			d.Entries[i].Code = code
 			d.Entries[i].Weight = weight
-			// Note: Moving an entry to a different group is complex.
-			// For now, we just update it in place.
+			d.Entries[i].Dirty = true
 			return
 		}
 	}
 
 	// If not found, add a new entry
-	newEntry := Entry{
+	d.insertIntoGroup(Entry{
 		Word:   word,
 		Code:   code,
 		Weight: weight,
-	}
+	}, group)
+}
 
-	// Find the target group and insert the new entry
-	groupFound := false
+// MoveToGroup moves the entry of word to the end of group. It reports
+// whether the entry was found in another group and moved.
+func (d *Dictionary) MoveToGroup(word, group string) bool {
+	current := ""
 	for i, entry := range d.Entries {
-		if entry.IsGroup && entry.Group == group {
-			// Insert after the group header
-			d.Entries = append(d.Entries[:i+1], append([]Entry{newEntry}, d.Entries[i+1:]...)...)
-			groupFound = true
-			break
+		if entry.IsGroup {
+			current = entry.Group
+			continue
 		}
-	}
-
-	// If group is not found, create it at the end of the file
-	if !groupFound {
-		d.Entries = append(d.Entries, Entry{IsGroup: true, Group: group})
-		d.Entries = append(d.Entries, newEntry)
-	}
-}
-
-// WubiEncoder can generate Wubi codes for Chinese words.
-type WubiEncoder struct {
-	charMap map[rune]string
-}
-
-// NewWubiEncoder creates an encoder by loading a main dictionary file.
-func NewWubiEncoder(mainDictPath string) (*WubiEncoder, error) {
-	charMap := make(map[rune]string)
-
-	file, err := os.Open(mainDictPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open main dictionary '%s': %w", mainDictPath, err)
-	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.HasPrefix(line, "#") || strings.TrimSpace(line) == "" {
+		if entry.IsComment || entry.Word != word {
 			continue
 		}
-		parts := strings.Split(line, "\t")
-		if len(parts) >= 2 {
-			char := []rune(parts[0])
-			if len(char) == 1 {
-				// We only care about single characters for building words
-				charMap[char[0]] = parts[1]
-			}
+		if current == group {
+			return false
 		}
+		d.Entries = append(d.Entries[:i], d.Entries[i+1:]...)
+		d.AppendToGroup(entry, group)
+		return true
 	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading main dictionary: %w", err)
-	}
-
-	return &WubiEncoder{charMap: charMap}, nil
+	return false
 }
 
-// GenerateCode generates a Wubi code for a given word.
-// Rules:
-// 1-char word: full code (up to 4 letters)
-// 2-char word: 1st letter of 1st char + 1st letter of 2nd char
-// 3-char word: 1st letter of 1st, 2nd, 3rd chars
-// 4+ char word: 1st letter of 1st, 2nd, 3rd chars + 1st letter of last char
-func (e *WubiEncoder) GenerateCode(word string) (string, error) {
-	runes := []rune(word)
-	if len(runes) == 0 {
-		return "", nil
-	}
-
-	if len(runes) == 1 {
-		code, ok := e.charMap[runes[0]]
-		if !ok {
-			return "", fmt.Errorf("character '%c' not found in main dictionary", runes[0])
+// AppendToGroup adds e after the last entry of group, so that entries
+// added one after another keep their order, or creates the group at the
+// end of the file if it does not exist.
+func (d *Dictionary) AppendToGroup(e Entry, group string) {
+	at := -1
+	for i, entry := range d.Entries {
+		if entry.IsGroup {
+			if at >= 0 {
+				break
+			}
+			if entry.Group == group {
+				at = i + 1
+			}
+			continue
+		}
+		if at >= 0 && entry.Word != "" {
+			at = i + 1
 		}
-		return code, nil
 	}
-
-	var generatedCode strings.Builder
-	var codesToTake []rune
-
-	if len(runes) <= 3 {
-		codesToTake = runes
-	} else { // 4 or more characters
-		codesToTake = []rune{runes[0], runes[1], runes[2], runes[len(runes)-1]}
+	if at < 0 {
+		d.Entries = append(d.Entries, Entry{IsGroup: true, Group: group}, e)
+		return
 	}
+	d.Entries = slices.Insert(d.Entries, at, e)
+}
 
-	for _, r := range codesToTake {
-		code, ok := e.charMap[r]
-		if !ok {
-			return "", fmt.Errorf("character '%c' not found in main dictionary", r)
-		}
-		if len(code) > 0 {
-			generatedCode.WriteByte(code[0])
+// insertIntoGroup inserts an entry right after the header of group, or
+// creates the group at the end of the file if it does not exist.
+func (d *Dictionary) insertIntoGroup(e Entry, group string) {
+	for i, entry := range d.Entries {
+		if entry.IsGroup && entry.Group == group {
+			d.Entries = append(d.Entries[:i+1], append([]Entry{e}, d.Entries[i+1:]...)...)
+			return
 		}
 	}
-
-	return generatedCode.String(), nil
+	d.Entries = append(d.Entries, Entry{IsGroup: true, Group: group}, e)
 }
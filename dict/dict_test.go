@@ -48,10 +48,10 @@ version: "1.0"
 	}
 
 	expectedEntries := []Entry{
-		{Word: "一丁", Code: "ag", Weight: 0, RawLine: "一丁\tag"},
-		{Word: "丁一", Code: "sa", Weight: 0, RawLine: "丁一\tsa"},
+		{Word: "一丁", Code: "ag", RawLine: "一丁\tag"},
+		{Word: "丁一", Code: "sa", RawLine: "丁一\tsa"},
 		{IsGroup: true, Group: "Custom Phrases", RawLine: "## Custom Phrases"},
-		{Word: "丁丁", Code: "ss", Weight: 0, RawLine: "丁丁\tss"},
+		{Word: "丁丁", Code: "ss", RawLine: "丁丁\tss"},
 	}
 
 	for i, entry := range d.Entries {
@@ -72,9 +72,9 @@ func TestDictionary_Save(t *testing.T) {
 		path:   dictPath,
 		Header: []string{"---", "..."},
 		Entries: []Entry{
-			{Word: "测试", Code: "iyf", Weight: 1},
+			{Word: "测试", Code: "iyf", Weight: AbsoluteWeight(1)},
 			{IsGroup: true, Group: "My Group"},
-			{Word: "词语", Code: "yiy", Weight: 0},
+			{Word: "词语", Code: "yiy", Weight: AbsoluteWeight(0)},
 			{IsComment: true, Comment: "# A comment"},
 		},
 	}
@@ -110,11 +110,11 @@ func TestDictionary_AddOrUpdate(t *testing.T) {
 	// Test updating an existing word
 	d := &Dictionary{
 		Entries: []Entry{
-			{Word: "word1", Code: "c1", Weight: 1},
+			{Word: "word1", Code: "c1", Weight: AbsoluteWeight(1)},
 		},
 	}
-	d.AddOrUpdate("word1", "new_code", 100, "group1")
-	if d.Entries[0].Code != "new_code" || d.Entries[0].Weight != 100 {
+	d.AddOrUpdate("word1", "new_code", AbsoluteWeight(100), "group1")
+	if d.Entries[0].Code != "new_code" || d.Entries[0].Weight != AbsoluteWeight(100) {
 		t.Errorf("Failed to update existing word. Got: %+v", d.Entries[0])
 	}
 
@@ -122,10 +122,10 @@ func TestDictionary_AddOrUpdate(t *testing.T) {
 	d = &Dictionary{
 		Entries: []Entry{
 			{IsGroup: true, Group: "group1"},
-			{Word: "word1", Code: "c1", Weight: 1},
+			{Word: "word1", Code: "c1", Weight: AbsoluteWeight(1)},
 		},
 	}
-	d.AddOrUpdate("word2", "c2", 2, "group1")
+	d.AddOrUpdate("word2", "c2", AbsoluteWeight(2), "group1")
 	if len(d.Entries) != 3 || d.Entries[1].Word != "word2" {
 		t.Errorf("Failed to add new word to existing group. Entries: %+v", d.Entries)
 	}
@@ -133,20 +133,83 @@ func TestDictionary_AddOrUpdate(t *testing.T) {
 	// Test adding a new word and creating a new group
 	d = &Dictionary{
 		Entries: []Entry{
-			{Word: "word1", Code: "c1", Weight: 1},
+			{Word: "word1", Code: "c1", Weight: AbsoluteWeight(1)},
 		},
 	}
-	d.AddOrUpdate("word2", "c2", 2, "new_group")
+	d.AddOrUpdate("word2", "c2", AbsoluteWeight(2), "new_group")
 	if len(d.Entries) != 3 || !d.Entries[1].IsGroup || d.Entries[1].Group != "new_group" || d.Entries[2].Word != "word2" {
 		t.Errorf("Failed to add new word and create new group. Entries: %+v", d.Entries)
 	}
 }
 
+func TestDictionary_MoveToGroup(t *testing.T) {
+	d := &Dictionary{
+		Entries: []Entry{
+			{IsGroup: true, Group: "group1"},
+			{Word: "word1", Code: "c1", RawLine: "word1\tc1"},
+			{Word: "word2", Code: "c2"},
+			{IsGroup: true, Group: "group2"},
+			{Word: "word3", Code: "c3"},
+		},
+	}
+	if d.MoveToGroup("word3", "group2") {
+		t.Error("An entry already in the group should not move")
+	}
+	if !d.MoveToGroup("word1", "group2") {
+		t.Fatal("Expected word1 to move")
+	}
+	if d.Entries[1].Word != "word2" || d.Entries[3].Word != "word3" || d.Entries[4].Word != "word1" {
+		t.Errorf("Unexpected order after moving: %+v", d.Entries)
+	}
+	if d.Entries[4].RawLine != "word1\tc1" {
+		t.Errorf("A moved entry should keep its line, got %+v", d.Entries[4])
+	}
+
+	if !d.MoveToGroup("word2", "group3") || !d.Entries[4].IsGroup || d.Entries[4].Group != "group3" || d.Entries[5].Word != "word2" {
+		t.Errorf("Moving to a missing group should create it at the end. Entries: %+v", d.Entries)
+	}
+	if d.MoveToGroup("missing", "group1") {
+		t.Error("A missing word should not move")
+	}
+}
+
+func TestDictionary_AppendToGroup(t *testing.T) {
+	d := &Dictionary{
+		Entries: []Entry{
+			{IsGroup: true, Group: "group1"},
+			{Word: "word1", Code: "c1"},
+			{IsComment: true},
+			{IsGroup: true, Group: "group2"},
+		},
+	}
+	for _, word := range []string{"a", "b", "c"} {
+		d.AppendToGroup(Entry{Word: word}, "group1")
+	}
+	d.AppendToGroup(Entry{Word: "d"}, "group2")
+	d.AppendToGroup(Entry{Word: "e"}, "group3")
+
+	var got []string
+	for _, e := range d.Entries {
+		switch {
+		case e.IsGroup:
+			got = append(got, "## "+e.Group)
+		case e.Word != "":
+			got = append(got, e.Word)
+		default:
+			got = append(got, "")
+		}
+	}
+	expected := []string{"## group1", "word1", "a", "b", "c", "", "## group2", "d", "## group3", "e"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected entries:\ngot:  %q\nwant: %q", got, expected)
+	}
+}
+
 func TestWubiEncoder_GenerateCode(t *testing.T) {
-	mainDictContent := `中	k
-国	l
-人	w
-民	n
+	mainDictContent := `中	khk
+国	lgyi
+人	wwww
+民	nav
 `
 	tempDir := t.TempDir()
 	mainDictPath := createTempDictFile(t, tempDir, mainDictContent)
@@ -161,9 +224,9 @@ func TestWubiEncoder_GenerateCode(t *testing.T) {
 		expected string
 		hasError bool
 	}{
-		{"中", "k", false},
-		{"中国", "kl", false},
-		{"中国人", "klw", false},
+		{"中", "khk", false},
+		{"中国", "khlg", false},
+		{"中国人", "klww", false},
 		{"中国人民", "klwn", false},
 		{"测试", "", true}, // "测" is not in the dictionary
 		{"", "", false},
@@ -181,3 +244,162 @@ func TestWubiEncoder_GenerateCode(t *testing.T) {
 		})
 	}
 }
+
+func TestDictionary_Columns(t *testing.T) {
+	testCases := []struct {
+		name     string
+		columns  string
+		line     string
+		expected Entry
+	}{
+		{"text-weight", "[text, weight]", "词语\t50", Entry{Word: "词语", Weight: AbsoluteWeight(50)}},
+		{"with-stem", "[text, code, stem, weight]", "词语\tyiyk\tyiy\t50", Entry{Word: "词语", Code: "yiyk", Stem: "yiy", Weight: AbsoluteWeight(50)}},
+		{"empty-stem", "[text, code, stem, weight]", "词语\tyiyk\t\t50", Entry{Word: "词语", Code: "yiyk", Weight: AbsoluteWeight(50)}},
+		{"weight-first", "[weight, text, code]", "50\t词语\tyiyk", Entry{Word: "词语", Code: "yiyk", Weight: AbsoluteWeight(50)}},
+		{"missing-optional", "[text, code, weight]", "词语\tyiyk", Entry{Word: "词语", Code: "yiyk"}},
+		{"text-only", "[text, code, weight]", "词语", Entry{Word: "词语"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			content := "---\ncolumns: " + tc.columns + "\n...\n" + tc.line + "\n"
+			dictPath := createTempDictFile(t, t.TempDir(), content)
+
+			d := NewDictionary(dictPath)
+			if err := d.Load(); err != nil {
+				t.Fatalf("Load() failed: %v", err)
+			}
+			if len(d.Entries) != 1 {
+				t.Fatalf("Expected 1 entry, got %d", len(d.Entries))
+			}
+			entry := d.Entries[0]
+			entry.RawLine = ""
+			if !reflect.DeepEqual(entry, tc.expected) {
+				t.Errorf("Entry mismatch:\ngot:  %+v\nwant: %+v", entry, tc.expected)
+			}
+
+			if err := d.Save(); err != nil {
+				t.Fatalf("Save() failed: %v", err)
+			}
+			saved, _ := os.ReadFile(dictPath)
+			d2 := NewDictionary(dictPath)
+			if err := d2.Load(); err != nil {
+				t.Fatalf("Load() after Save() failed: %v\n%s", err, saved)
+			}
+			entry2 := d2.Entries[0]
+			entry2.RawLine = ""
+			if !reflect.DeepEqual(entry2, tc.expected) {
+				t.Errorf("Entry changed after Save():\ngot:  %+v\nwant: %+v\nfile:\n%s", entry2, tc.expected, saved)
+			}
+		})
+	}
+}
+
+func TestDictionary_SaveColumns(t *testing.T) {
+	dictPath := filepath.Join(t.TempDir(), "columns.dict.yaml")
+	d := &Dictionary{
+		path:   dictPath,
+		Header: []string{"---", "columns:", "  - text", "  - weight", "  - code", "..."},
+		Meta:   Metadata{Columns: []string{"text", "weight", "code"}},
+		Entries: []Entry{
+			{Word: "测试", Code: "imya", Weight: AbsoluteWeight(10)},
+		},
+	}
+	if err := d.Save(); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	content, _ := os.ReadFile(dictPath)
+	expected := "---\ncolumns:\n  - text\n  - weight\n  - code\n...\n测试\t10\timya\n"
+	if string(content) != expected {
+		t.Errorf("Saved content mismatch:\ngot:\n%s\nwant:\n%s", content, expected)
+	}
+}
+
+func TestDictionary_LoadInvalidColumns(t *testing.T) {
+	dictPath := createTempDictFile(t, t.TempDir(), "---\ncolumns: [code, weight]\n...\n")
+	if err := NewDictionary(dictPath).Load(); err == nil {
+		t.Error("Expected an error for columns without 'text'")
+	}
+}
+
+func TestDictionary_RoundTrip(t *testing.T) {
+	testCases := map[string]string{
+		"jidian": `# Rime dictionary
+# encoding: utf-8
+---
+name: wubi86_jidian_user
+version: "1.0"   # trailing comment
+sort: by_weight
+use_preset_vocabulary: false
+...
+##   Spaced Group
+一丁	ag
+丁一	sa	0
+词语	yiyk	100	extra	fields
+
+#no space comment
+##Tight Group
+  indented line
+丁丁	ss	010
+`,
+		"no-final-newline": "---\nname: test\n...\nword\tcode\t1",
+		"no-header":        "中\tkhk\n国\tlgyi\n",
+		"no-header-start":  "# comment\nname: x\ncolumns: [text, weight]\n...\n词语\t5%\n",
+		"columns": `---
+columns: [text, weight]
+...
+词语	5%
+词语
+`,
+		"empty": "",
+	}
+
+	for name, content := range testCases {
+		t.Run(name, func(t *testing.T) {
+			dictPath := createTempDictFile(t, t.TempDir(), content)
+
+			d := NewDictionary(dictPath)
+			if err := d.Load(); err != nil {
+				t.Fatalf("Load() failed: %v", err)
+			}
+			if err := d.Save(); err != nil {
+				t.Fatalf("Save() failed: %v", err)
+			}
+
+			saved, _ := os.ReadFile(dictPath)
+			if string(saved) != content {
+				t.Errorf("Round trip changed the file:\ngot:\n%q\nwant:\n%q", saved, content)
+			}
+		})
+	}
+}
+
+func TestDictionary_SaveOnlyDirtyLines(t *testing.T) {
+	content := "---\n...\n##  Group\nword1\tcode1\nword2\tcode2\t10\textra\nword3\tcode3\t010\n"
+	dictPath := createTempDictFile(t, t.TempDir(), content)
+
+	d := NewDictionary(dictPath)
+	if err := d.Load(); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	d.AddOrUpdate("word2", "code2", AbsoluteWeight(20), "Group")
+
+	if err := d.Save(); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	saved, _ := os.ReadFile(dictPath)
+	expected := "---\n...\n##  Group\nword1\tcode1\nword2\tcode2\t20\textra\nword3\tcode3\t010\n"
+	if string(saved) != expected {
+		t.Errorf("Saved content mismatch:\ngot:\n%q\nwant:\n%q", saved, expected)
+	}
+}
+
+// readFile returns the content of path, failing the test on error.
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	return string(content)
+}
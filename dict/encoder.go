@@ -0,0 +1,86 @@
+package dict
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Encoder generates the code of a word for one input method.
+type Encoder interface {
+	GenerateCode(word string) (string, error)
+}
+
+// EncoderOptions is what an encoder can be built from.
+type EncoderOptions struct {
+	Main  *MergedView    // The main dictionary and the tables it imports
+	Rules []EncodingRule // Phrase rules from the schema or the user, if any
+	// Chars replaces the codes the main dictionary gives these
+	// characters, e.g. as collected by CollectCharCodes.
+	Chars map[rune][]string
+	// NonHan is what to do with characters that are not Chinese.
+	NonHan NonHanPolicy
+}
+
+// EncoderFactory builds an encoder for a scheme.
+type EncoderFactory func(opts EncoderOptions) (Encoder, error)
+
+var encoderFactories = make(map[string]EncoderFactory)
+
+// RegisterScheme makes an encoder available under name. It panics if the
+// name is already taken, as that is a programming error.
+func RegisterScheme(name string, factory EncoderFactory) {
+	if _, ok := encoderFactories[name]; ok {
+		panic(fmt.Sprintf("dict: scheme '%s' registered twice", name))
+	}
+	encoderFactories[name] = factory
+}
+
+// Schemes returns the names of the registered schemes, sorted.
+func Schemes() []string {
+	names := make([]string, 0, len(encoderFactories))
+	for name := range encoderFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewEncoder builds the encoder of the named scheme. The encoder is
+// wrapped in a NonHanEncoder applying opts.NonHan.
+func NewEncoder(scheme string, opts EncoderOptions) (Encoder, error) {
+	factory, ok := encoderFactories[scheme]
+	if !ok {
+		return nil, fmt.Errorf("unknown scheme '%s': must be one of %s", scheme, strings.Join(Schemes(), ", "))
+	}
+	if opts.Main == nil {
+		opts.Main = &MergedView{}
+	}
+	enc, err := factory(opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.NonHan == NonHanLetter {
+		learnLetters(enc)
+	}
+	if err := overrideChars(enc, opts.Chars); err != nil {
+		return nil, fmt.Errorf("scheme '%s': %w", scheme, err)
+	}
+	return &NonHanEncoder{Encoder: enc, Policy: opts.NonHan}, nil
+}
+
+func init() {
+	RegisterScheme("table", func(opts EncoderOptions) (Encoder, error) {
+		if len(opts.Rules) == 0 {
+			return nil, fmt.Errorf("the table scheme needs encoder rules from --schema or --rules")
+		}
+		return NewTableEncoderFromView(opts.Main, opts.Rules), nil
+	})
+	RegisterScheme("wubi86", func(opts EncoderOptions) (Encoder, error) {
+		rules := opts.Rules
+		if len(rules) == 0 {
+			rules = Wubi86Rules
+		}
+		return NewTableEncoderFromView(opts.Main, rules), nil
+	})
+}
@@ -0,0 +1,49 @@
+package dict
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSchemes(t *testing.T) {
+	schemes := Schemes()
+	for _, name := range []string{"table", "wubi86"} {
+		if !slices.Contains(schemes, name) {
+			t.Errorf("Schemes() = %v, missing %s", schemes, name)
+		}
+	}
+	if !slices.IsSorted(schemes) {
+		t.Errorf("Schemes() = %v, want sorted", schemes)
+	}
+}
+
+func TestNewEncoder(t *testing.T) {
+	view, err := LoadMerged("", wubiSampleDict)
+	if err != nil {
+		t.Fatalf("LoadMerged failed: %v", err)
+	}
+
+	encoder, err := NewEncoder("wubi86", EncoderOptions{Main: view})
+	if err != nil {
+		t.Fatalf("NewEncoder(wubi86) failed: %v", err)
+	}
+	if code, err := encoder.GenerateCode("中国"); err != nil || code != "khlg" {
+		t.Errorf("GenerateCode(中国) = %s, %v; want khlg", code, err)
+	}
+
+	encoder, err = NewEncoder("table", EncoderOptions{Main: view, Rules: []EncodingRule{{MinLength: 2, MaxLength: 2, Formula: "AaBa"}}})
+	if err != nil {
+		t.Fatalf("NewEncoder(table) failed: %v", err)
+	}
+	if code, err := encoder.GenerateCode("中国"); err != nil || code != "kl" {
+		t.Errorf("GenerateCode(中国) = %s, %v; want kl", code, err)
+	}
+
+	if _, err := NewEncoder("table", EncoderOptions{Main: view}); err == nil {
+		t.Error("NewEncoder(table) should fail without rules")
+	}
+	if _, err := NewEncoder("dvorak", EncoderOptions{}); err == nil || !strings.Contains(err.Error(), "wubi86") {
+		t.Errorf("NewEncoder should reject an unknown scheme and list the known ones, got: %v", err)
+	}
+}
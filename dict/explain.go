@@ -0,0 +1,85 @@
+package dict
+
+import "errors"
+
+// Explanation tells how a TableEncoder derives the code of a word.
+type Explanation struct {
+	Word  string
+	Rule  *EncodingRule // The rule applied; nil for a single character without one
+	Chars []CharCodes   // Every character of the word, in order
+	Picks []LetterPick  // One per letter pair of the rule's formula
+	Code  string
+	// Candidates lists every possible code if a character has several
+	// full codes that lead to different results. Chars and Picks then
+	// show the derivation from the first full code of each character.
+	Candidates []string
+}
+
+// CharCodes lists the codes of a character in the main dictionary.
+type CharCodes struct {
+	Char       rune
+	FullCodes  []string // The longest codes, which phrases are built from
+	OtherCodes []string // Shorter codes, such as simple codes
+}
+
+// LetterPick is what one letter pair of a formula took from the word.
+type LetterPick struct {
+	Pair      string // The pair, e.g. "Ab"
+	Char      rune
+	CharIndex int    // Index of the character in the word, -1 if out of range
+	FullCode  string // The full code of the character
+	CodeIndex int    // Index of the letter in FullCode, -1 if skipped
+	Letter    string
+	Skipped   bool // The pair points past the word or code, or repeats a letter
+}
+
+// Explainer is implemented by encoders that can explain their codes.
+type Explainer interface {
+	Explain(word string) (*Explanation, error)
+}
+
+// Explain reports the codes of each character of word, the rule used and
+// the letters it took. It fails where GenerateCode fails, except that an
+// ambiguous code is explained with its candidates.
+func (e *TableEncoder) Explain(word string) (*Explanation, error) {
+	code, err := e.GenerateCode(word)
+	var ambiguous *AmbiguousCodeError
+	if err != nil && !errors.As(err, &ambiguous) {
+		return nil, err
+	}
+
+	runes := []rune(word)
+	x := &Explanation{Word: word, Code: code}
+	if ambiguous != nil {
+		x.Candidates = ambiguous.Candidates
+	}
+	rule, coords, err := e.formula(len(runes))
+	if err != nil {
+		return nil, err
+	}
+	x.Rule = rule
+
+	codes := make(map[int]string)
+	for i, r := range runes {
+		chars := CharCodes{Char: r, FullCodes: e.fullCodes(r)}
+		for _, c := range e.charMap[r] {
+			if len(chars.FullCodes) > 0 && len(c) < len(chars.FullCodes[0]) {
+				chars.OtherCodes = append(chars.OtherCodes, c)
+			}
+		}
+		if len(chars.FullCodes) > 0 {
+			codes[i] = chars.FullCodes[0]
+		}
+		x.Chars = append(x.Chars, chars)
+	}
+
+	derived, picks := applyCoords(coords, runes, codes)
+	for i := range picks {
+		picks[i].Pair = rule.Formula[2*i : 2*i+2]
+	}
+	x.Picks = picks
+	if x.Code == "" {
+		x.Code = derived
+	}
+	return x, nil
+}
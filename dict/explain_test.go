@@ -0,0 +1,68 @@
+package dict
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTableEncoder_Explain(t *testing.T) {
+	encoder, err := NewWubiEncoder(wubiSampleDict)
+	if err != nil {
+		t.Fatalf("NewWubiEncoder failed: %v", err)
+	}
+
+	x, err := encoder.Explain("中国人")
+	if err != nil {
+		t.Fatalf("Explain failed: %v", err)
+	}
+	if x.Code != "klww" || x.Rule == nil || x.Rule.Formula != "AaBaCaCb" {
+		t.Errorf("Explain(中国人) = code %s, rule %v", x.Code, x.Rule)
+	}
+	expectedChars := []CharCodes{
+		{Char: '中', FullCodes: []string{"khk"}, OtherCodes: []string{"k"}},
+		{Char: '国', FullCodes: []string{"lgyi"}, OtherCodes: []string{"l"}},
+		{Char: '人', FullCodes: []string{"wwww"}, OtherCodes: []string{"w"}},
+	}
+	if !reflect.DeepEqual(x.Chars, expectedChars) {
+		t.Errorf("Chars = %+v, want %+v", x.Chars, expectedChars)
+	}
+	var taken []string
+	for _, p := range x.Picks {
+		taken = append(taken, p.Pair+":"+string(p.Char)+p.Letter)
+	}
+	if got := strings.Join(taken, " "); got != "Aa:中k Ba:国l Ca:人w Cb:人w" {
+		t.Errorf("Picks = %s", got)
+	}
+
+	x, err = encoder.Explain("国")
+	if err != nil || x.Rule != nil || x.Code != "lgyi" || len(x.Picks) != 0 {
+		t.Errorf("Explain(国) = %+v, %v", x, err)
+	}
+}
+
+func TestTableEncoder_ExplainSkippedAndAmbiguous(t *testing.T) {
+	main := "日\ta\n月\tb\n甲\tabcd\n甲\tefgh\n丙\tgmwi\n"
+	encoder, _ := NewTableEncoderFromReader(strings.NewReader(main), cangjie5Rules)
+
+	x, err := encoder.Explain("日月")
+	if err != nil {
+		t.Fatalf("Explain failed: %v", err)
+	}
+	if x.Code != "ab" || len(x.Picks) != 5 || !x.Picks[1].Skipped || x.Picks[1].Pair != "Az" {
+		t.Errorf("Explain(日月) = %+v", x)
+	}
+
+	encoder.Rules = Wubi86Rules
+	x, err = encoder.Explain("甲丙")
+	if err != nil {
+		t.Fatalf("Explain failed: %v", err)
+	}
+	if !reflect.DeepEqual(x.Candidates, []string{"abgm", "efgm"}) || x.Code != "abgm" {
+		t.Errorf("Explain(甲丙) = code %s, candidates %v", x.Code, x.Candidates)
+	}
+
+	if _, err := encoder.Explain("甲乙"); err == nil {
+		t.Error("Explain should fail on a missing character")
+	}
+}
@@ -0,0 +1,99 @@
+package dict
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// EntryFilter selects entries of a MergedView. Zero fields match every
+// entry.
+type EntryFilter struct {
+	Groups     []string       // Entries in any of these '##' groups
+	Word       *regexp.Regexp // Entries whose word matches
+	CodePrefix string         // Entries whose code starts with this
+	// MinWeight and MaxWeight bound the weight, inclusively; an absent
+	// weight is no bound. Only weights of the bound's kind match it, so a
+	// percentage bound never selects an absolute weight.
+	MinWeight, MaxWeight Weight
+}
+
+// Match reports whether the filter selects e.
+func (f EntryFilter) Match(e SourcedEntry) bool {
+	if len(f.Groups) > 0 && !slices.Contains(f.Groups, e.Group) {
+		return false
+	}
+	if f.Word != nil && !f.Word.MatchString(e.Entry.Word) {
+		return false
+	}
+	if !strings.HasPrefix(e.Entry.Code, f.CodePrefix) {
+		return false
+	}
+	w := e.Entry.Weight
+	if !f.MinWeight.IsAbsent() && (w.Kind != f.MinWeight.Kind || w.Value < f.MinWeight.Value) {
+		return false
+	}
+	if !f.MaxWeight.IsAbsent() && (w.Kind != f.MaxWeight.Kind || w.Value > f.MaxWeight.Value) {
+		return false
+	}
+	return true
+}
+
+// Filter returns a view holding only the entries f selects.
+func (v *MergedView) Filter(f EntryFilter) *MergedView {
+	filtered := &MergedView{Root: v.Root, Sources: v.Sources}
+	for _, e := range v.Entries {
+		if f.Match(e) {
+			filtered.Entries = append(filtered.Entries, e)
+		}
+	}
+	return filtered
+}
+
+// ExportFormats lists the formats WriteEntries accepts. The CSV and TSV
+// columns are those ParseWordList reads back.
+var ExportFormats = []string{"json", "csv", "tsv"}
+
+// exportedEntry is an entry as written by WriteEntries.
+type exportedEntry struct {
+	Word   string `json:"word"`
+	Code   string `json:"code"`
+	Weight string `json:"weight,omitempty"`
+	Group  string `json:"group,omitempty"`
+}
+
+// WriteEntries writes entries to w as JSON, CSV or TSV.
+func WriteEntries(w io.Writer, format string, entries []SourcedEntry) error {
+	rows := make([]exportedEntry, 0, len(entries))
+	for _, e := range entries {
+		rows = append(rows, exportedEntry{Word: e.Entry.Word, Code: e.Entry.Code, Weight: e.Entry.Weight.String(), Group: e.Group})
+	}
+
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false)
+		return encoder.Encode(rows)
+	case "csv":
+		writer := csv.NewWriter(w)
+		writer.Write([]string{"word", "code", "weight", "group"})
+		for _, r := range rows {
+			writer.Write([]string{r.Word, r.Code, r.Weight, r.Group})
+		}
+		writer.Flush()
+		return writer.Error()
+	case "tsv":
+		lines := []string{"word\tcode\tweight\tgroup"}
+		for _, r := range rows {
+			lines = append(lines, strings.Join([]string{r.Word, r.Code, r.Weight, r.Group}, "\t"))
+		}
+		_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+		return err
+	}
+	return fmt.Errorf("invalid format '%s': must be one of %s", format, strings.Join(ExportFormats, ", "))
+}
@@ -0,0 +1,77 @@
+package dict
+
+import (
+	"bytes"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestEntryFilter(t *testing.T) {
+	dir := t.TempDir()
+	writeDictFiles(t, dir, map[string]string{
+		"user.dict.yaml": "---\nname: user\n...\n## 工作\n用例\tetwg\t200\n解耦\tqedi\t100\n## 个人\n哈希\tkqqd\n幂等\tpjtf\t150\n散列\tanuj\t5%\n",
+	})
+	v, err := LoadMerged(dir, filepath.Join(dir, "user.dict.yaml"))
+	if err != nil {
+		t.Fatalf("LoadMerged failed: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		filter   EntryFilter
+		expected string
+	}{
+		{"no filter", EntryFilter{}, "用例 解耦 哈希 幂等 散列"},
+		{"group", EntryFilter{Groups: []string{"个人"}}, "哈希 幂等 散列"},
+		{"word pattern", EntryFilter{Word: regexp.MustCompile("^[用幂]")}, "用例 幂等"},
+		{"code prefix", EntryFilter{CodePrefix: "qe"}, "解耦"},
+		{"weight range", EntryFilter{MinWeight: AbsoluteWeight(120), MaxWeight: AbsoluteWeight(200)}, "用例 幂等"},
+		{"absolute bound skips percentages", EntryFilter{MaxWeight: AbsoluteWeight(10)}, ""},
+		{"percentage bound", EntryFilter{MinWeight: PercentWeight(5)}, "散列"},
+		{"percentage bound skips absolute weights", EntryFilter{MaxWeight: PercentWeight(50)}, "散列"},
+		{"combined", EntryFilter{Groups: []string{"工作"}, MinWeight: AbsoluteWeight(120)}, "用例"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := strings.Join(entryWords(v.Filter(tc.filter).Entries), " "); got != tc.expected {
+				t.Errorf("Filter = %s, want %s", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestWriteEntries(t *testing.T) {
+	entries := []SourcedEntry{
+		{Entry: Entry{Word: "用例", Code: "etwg", Weight: AbsoluteWeight(200)}, Group: "工作"},
+		{Entry: Entry{Word: "哈希", Code: "kqqd"}},
+	}
+
+	expected := map[string]string{
+		"tsv":  "word\tcode\tweight\tgroup\n用例\tetwg\t200\t工作\n哈希\tkqqd\t\t\n",
+		"csv":  "word,code,weight,group\n用例,etwg,200,工作\n哈希,kqqd,,\n",
+		"json": "[\n  {\n    \"word\": \"用例\",\n    \"code\": \"etwg\",\n    \"weight\": \"200\",\n    \"group\": \"工作\"\n  },\n  {\n    \"word\": \"哈希\",\n    \"code\": \"kqqd\"\n  }\n]\n",
+	}
+	for _, format := range ExportFormats {
+		var buf bytes.Buffer
+		if err := WriteEntries(&buf, format, entries); err != nil {
+			t.Fatalf("WriteEntries(%s) failed: %v", format, err)
+		}
+		if buf.String() != expected[format] {
+			t.Errorf("WriteEntries(%s) = %q, want %q", format, buf.String(), expected[format])
+		}
+	}
+
+	// TSV and CSV exports can be imported again.
+	for _, format := range []string{"tsv", "csv"} {
+		rows, err := ParseWordList(strings.NewReader(expected[format]), format)
+		if err != nil || len(rows) != 2 || rows[0].Word != "用例" || rows[0].Weight != "200" || rows[0].Group != "工作" {
+			t.Errorf("ParseWordList(%s export) = %+v, %v", format, rows, err)
+		}
+	}
+
+	if err := WriteEntries(&bytes.Buffer{}, "xml", entries); err == nil {
+		t.Error("WriteEntries should reject an unknown format")
+	}
+}
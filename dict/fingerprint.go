@@ -0,0 +1,68 @@
+package dict
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// ErrModifiedExternally is returned by Save when the dictionary file was
+// changed by another program after it was loaded.
+var ErrModifiedExternally = errors.New("dictionary file was changed by another program since it was loaded")
+
+// Fingerprint identifies the content of a file at a point in time.
+type Fingerprint struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+	Hash    string // Hex encoded SHA-256 of the content
+}
+
+// FingerprintFile computes the fingerprint of the file at path.
+func FingerprintFile(path string) (Fingerprint, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Fingerprint{}, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return Fingerprint{}, err
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return Fingerprint{}, err
+	}
+	return Fingerprint{Path: path, Size: info.Size(), ModTime: info.ModTime(), Hash: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// SameContent reports whether f and other describe the same file content.
+// A new modification time alone, e.g. from touch, does not count as a
+// change.
+func (f Fingerprint) SameContent(other Fingerprint) bool {
+	return f.Size == other.Size && f.Hash == other.Hash
+}
+
+// checkUnchanged returns ErrModifiedExternally if the file differs from
+// what was loaded.
+func (d *Dictionary) checkUnchanged() error {
+	if d.loaded == nil {
+		return nil
+	}
+	current, err := FingerprintFile(d.path)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%w: '%s' was removed", ErrModifiedExternally, d.path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check dictionary file: %w", err)
+	}
+	if !d.loaded.SameContent(current) {
+		return fmt.Errorf("%w: '%s' (modified at %s)", ErrModifiedExternally, d.path, current.ModTime.Format("2006-01-02 15:04:05"))
+	}
+	return nil
+}
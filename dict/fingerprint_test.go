@@ -0,0 +1,75 @@
+package dict
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestDictionary_SaveDetectsExternalEdits(t *testing.T) {
+	dictPath := createTempDictFile(t, t.TempDir(), "---\n...\nword\tcode\t1\n")
+
+	d := NewDictionary(dictPath)
+	if err := d.Load(); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	external := "---\n...\nword\tcode\t1\nother\tcode\t2\n"
+	if err := os.WriteFile(dictPath, []byte(external), 0o644); err != nil {
+		t.Fatalf("Failed to simulate an external edit: %v", err)
+	}
+
+	d.AddOrUpdate("word", "code", AbsoluteWeight(5), "")
+	if err := d.Save(); !errors.Is(err, ErrModifiedExternally) {
+		t.Fatalf("Expected ErrModifiedExternally, got %v", err)
+	}
+	if got := readFile(t, dictPath); got != external {
+		t.Errorf("Save() overwrote the external edit: %q", got)
+	}
+
+	// After a reload the change can be saved, and saved again.
+	if err := d.Load(); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	d.AddOrUpdate("word", "code", AbsoluteWeight(5), "")
+	if err := d.Save(); err != nil {
+		t.Fatalf("Save() after reload failed: %v", err)
+	}
+	d.AddOrUpdate("word", "code", AbsoluteWeight(6), "")
+	if err := d.Save(); err != nil {
+		t.Fatalf("Second Save() failed: %v", err)
+	}
+	if got := readFile(t, dictPath); got != "---\n...\nword\tcode\t6\nother\tcode\t2\n" {
+		t.Errorf("Unexpected content: %q", got)
+	}
+}
+
+func TestDictionary_SaveIgnoresTouch(t *testing.T) {
+	dictPath := createTempDictFile(t, t.TempDir(), "---\n...\nword\tcode\t1\n")
+
+	d := NewDictionary(dictPath)
+	if err := d.Load(); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(dictPath, later, later); err != nil {
+		t.Fatalf("Chtimes failed: %v", err)
+	}
+	if err := d.Save(); err != nil {
+		t.Errorf("Save() should ignore a new modification time alone, got %v", err)
+	}
+}
+
+func TestFingerprintFile(t *testing.T) {
+	dictPath := createTempDictFile(t, t.TempDir(), "abc")
+
+	fp, err := FingerprintFile(dictPath)
+	if err != nil {
+		t.Fatalf("FingerprintFile() failed: %v", err)
+	}
+	// SHA-256 of "abc".
+	if fp.Size != 3 || fp.Hash != "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad" {
+		t.Errorf("Unexpected fingerprint: %+v", fp)
+	}
+}
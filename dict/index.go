@@ -0,0 +1,82 @@
+package dict
+
+import (
+	"sort"
+	"strings"
+)
+
+// CodeIndex finds the entries on a code, for reverse lookups.
+type CodeIndex struct {
+	entries []SourcedEntry // Sorted by code, then as rankedBefore orders weights
+}
+
+// NewCodeIndex indexes every entry of the given views.
+func NewCodeIndex(views ...*MergedView) *CodeIndex {
+	x := &CodeIndex{}
+	for _, v := range views {
+		for _, e := range v.Entries {
+			if e.Entry.Code != "" {
+				x.entries = append(x.entries, e)
+			}
+		}
+	}
+	x.sort()
+	return x
+}
+
+func (x *CodeIndex) sort() {
+	sort.SliceStable(x.entries, func(i, j int) bool {
+		a, b := x.entries[i].Entry, x.entries[j].Entry
+		if a.Code != b.Code {
+			return a.Code < b.Code
+		}
+		return rankedBefore(a.Weight, b.Weight)
+	})
+}
+
+// weightRanks orders the kinds of weights in an index: absolute weights
+// first, then percentages, then entries without a usable weight.
+var weightRanks = map[WeightKind]int{WeightAbsolute: 0, WeightPercent: 1, WeightAbsent: 2, WeightInvalid: 3}
+
+// rankedBefore is a total order on weights: by kind, then heaviest first
+// within a kind.
+func rankedBefore(a, b Weight) bool {
+	if a.Kind != b.Kind {
+		return weightRanks[a.Kind] < weightRanks[b.Kind]
+	}
+	return a.Value > b.Value
+}
+
+// Merge returns an index of the entries of both x and other. On equal
+// codes and weights, entries of x come first.
+func (x *CodeIndex) Merge(other *CodeIndex) *CodeIndex {
+	merged := &CodeIndex{entries: append(append([]SourcedEntry(nil), x.entries...), other.entries...)}
+	merged.sort()
+	return merged
+}
+
+// Len returns the number of indexed entries.
+func (x *CodeIndex) Len() int {
+	return len(x.entries)
+}
+
+// Lookup returns the entries on exactly code.
+func (x *CodeIndex) Lookup(code string) []SourcedEntry {
+	i := sort.Search(len(x.entries), func(i int) bool { return x.entries[i].Entry.Code >= code })
+	j := i
+	for j < len(x.entries) && x.entries[j].Entry.Code == code {
+		j++
+	}
+	return x.entries[i:j:j]
+}
+
+// WithPrefix returns the entries on codes that start with prefix and are
+// longer than it, sorted by code.
+func (x *CodeIndex) WithPrefix(prefix string) []SourcedEntry {
+	i := sort.Search(len(x.entries), func(i int) bool { return x.entries[i].Entry.Code > prefix })
+	j := i
+	for j < len(x.entries) && strings.HasPrefix(x.entries[j].Entry.Code, prefix) {
+		j++
+	}
+	return x.entries[i:j:j]
+}
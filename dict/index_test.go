@@ -0,0 +1,103 @@
+package dict
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func entryWords(entries []SourcedEntry) []string {
+	var words []string
+	for _, e := range entries {
+		words = append(words, e.Entry.Word)
+	}
+	return words
+}
+
+func TestCodeIndex(t *testing.T) {
+	dir := t.TempDir()
+	writeDictFiles(t, dir, map[string]string{
+		"main.dict.yaml": "---\nname: main\n...\n中\tkhk\n中国\tkhlg\t10\n口\tkkkk\n兄\tkq\n另\tkl\t5\n号\tkgnb\n叶\tkf\t20\n叶\tkfh\n只\tkw\t30\n吗\tkcg\n吧\tkcn\n",
+		"user.dict.yaml": "---\nname: user\n...\n中坚\tkhjc\n口子\tkhlg\t50\n",
+	})
+	mainView, _ := LoadMerged(dir, filepath.Join(dir, "main.dict.yaml"))
+	userView, _ := LoadMerged(dir, filepath.Join(dir, "user.dict.yaml"))
+	index := NewCodeIndex(userView).Merge(NewCodeIndex(mainView))
+
+	if index.Len() != 13 {
+		t.Errorf("Len() = %d, want 13", index.Len())
+	}
+
+	exact := index.Lookup("khlg")
+	if words := entryWords(exact); len(words) != 2 || words[0] != "口子" || words[1] != "中国" {
+		t.Errorf("Lookup(khlg) = %v, want the heavier 口子 first", words)
+	}
+	if exact[0].Source != filepath.Join(dir, "user.dict.yaml") {
+		t.Errorf("Lookup(khlg) source = %s", exact[0].Source)
+	}
+	if len(index.Lookup("kz")) != 0 {
+		t.Error("Lookup(kz) should find nothing")
+	}
+
+	prefix := entryWords(index.WithPrefix("kh"))
+	expected := []string{"中坚", "中", "口子", "中国"}
+	if len(prefix) != len(expected) {
+		t.Fatalf("WithPrefix(kh) = %v, want %v", prefix, expected)
+	}
+	for i := range expected {
+		if prefix[i] != expected[i] {
+			t.Errorf("WithPrefix(kh) = %v, want %v", prefix, expected)
+			break
+		}
+	}
+	if words := entryWords(index.WithPrefix("kf")); len(words) != 1 || words[0] != "叶" {
+		t.Errorf("WithPrefix(kf) = %v, want only the longer code", words)
+	}
+}
+
+func TestCodeIndex_MixedWeightKinds(t *testing.T) {
+	entries := []Entry{
+		{Word: "甲", Code: "aa", Weight: PercentWeight(50)},
+		{Word: "乙", Code: "aa"},
+		{Word: "丙", Code: "aa", Weight: AbsoluteWeight(1)},
+		{Word: "丁", Code: "aa", Weight: PercentWeight(90)},
+		{Word: "戊", Code: "aa", Weight: AbsoluteWeight(10)},
+		{Word: "己", Code: "aa", Weight: InvalidWeight("x")},
+	}
+	expected := "戊 丙 丁 甲 乙 己"
+
+	// The order must not depend on the order of the input.
+	for shift := range entries {
+		v := &MergedView{}
+		for i := range entries {
+			v.Entries = append(v.Entries, SourcedEntry{Entry: entries[(i+shift)%len(entries)]})
+		}
+		if got := strings.Join(entryWords(NewCodeIndex(v).Lookup("aa")), " "); got != expected {
+			t.Errorf("Shift %d: Lookup(aa) = %s, want %s", shift, got, expected)
+		}
+	}
+}
+
+func TestCache_LoadCodeIndex(t *testing.T) {
+	dir := t.TempDir()
+	writeDictFiles(t, dir, map[string]string{"main.dict.yaml": "中\tkhk\n国\tlgyi\t8\n"})
+	mainPath := filepath.Join(dir, "main.dict.yaml")
+	cache := &Cache{Dir: filepath.Join(dir, "cache")}
+
+	for i := 0; i < 2; i++ {
+		index, err := cache.LoadCodeIndex(dir, mainPath)
+		if err != nil {
+			t.Fatalf("LoadCodeIndex() failed: %v", err)
+		}
+		found := index.Lookup("lgyi")
+		if len(found) != 1 || found[0].Entry.Word != "国" || found[0].Entry.Weight != AbsoluteWeight(8) || found[0].Source != mainPath {
+			t.Errorf("Load %d: Lookup(lgyi) = %+v", i+1, found)
+		}
+	}
+	// The index is built from the cached view rather than stored again.
+	cache.LoadMerged(dir, mainPath)
+	if files, _ := os.ReadDir(cache.Dir); len(files) != 1 {
+		t.Errorf("Expected one cache file, got %d", len(files))
+	}
+}
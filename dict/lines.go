@@ -0,0 +1,51 @@
+package dict
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// utf8BOM is the byte order mark some Windows editors put at the start of
+// UTF-8 files.
+const utf8BOM = "\xef\xbb\xbf"
+
+// lineReader reads lines of any length from a stream. Unlike
+// bufio.Scanner it has no maximum line size. A leading UTF-8 BOM is
+// stripped and remembered.
+type lineReader struct {
+	r      *bufio.Reader
+	read   bool
+	hasBOM bool
+}
+
+func newLineReader(r io.Reader) *lineReader {
+	return &lineReader{r: bufio.NewReader(r)}
+}
+
+// next returns the next line without its terminator, and the terminator
+// itself: "\n", "\r\n", or "" for a last line without one. It returns
+// io.EOF once there are no more lines.
+func (lr *lineReader) next() (line, eol string, err error) {
+	line, err = lr.r.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", "", err
+	}
+	if !lr.read {
+		lr.read = true
+		if strings.HasPrefix(line, utf8BOM) {
+			lr.hasBOM = true
+			line = strings.TrimPrefix(line, utf8BOM)
+		}
+	}
+	if err == io.EOF {
+		if line == "" {
+			return "", "", io.EOF
+		}
+		return line, "", nil
+	}
+	if strings.HasSuffix(line, "\r\n") {
+		return line[:len(line)-2], "\r\n", nil
+	}
+	return line[:len(line)-1], "\n", nil
+}
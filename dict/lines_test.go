@@ -0,0 +1,167 @@
+package dict
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParse_LongLines(t *testing.T) {
+	longWord := strings.Repeat("长", 100*1024)
+	content := "---\nname: long\n...\n" + longWord + "\tcode\t1\n"
+
+	d, err := Parse(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	if len(d.Entries) != 1 || d.Entries[0].Word != longWord {
+		t.Fatalf("Long entry was not parsed, got %d entries", len(d.Entries))
+	}
+	if d.Meta.Name != "long" {
+		t.Errorf("Expected name 'long', got '%s'", d.Meta.Name)
+	}
+
+	var buf bytes.Buffer
+	n, err := d.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	if buf.String() != content || n != int64(len(content)) {
+		t.Errorf("WriteTo() did not reproduce the input (wrote %d bytes, want %d)", n, len(content))
+	}
+}
+
+func TestParse_SaveWithoutPath(t *testing.T) {
+	d, err := Parse(strings.NewReader("---\n...\nword\tcode\n"))
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	if err := d.Save(); err == nil {
+		t.Error("Save() should fail for a dictionary without a path")
+	}
+}
+
+func TestNewWubiEncoderFromReader_LongLines(t *testing.T) {
+	content := "# " + strings.Repeat("x", 100*1024) + "\n中\tk\n"
+
+	encoder, err := NewWubiEncoderFromReader(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("NewWubiEncoderFromReader() failed: %v", err)
+	}
+	if code, err := encoder.GenerateCode("中"); err != nil || code != "k" {
+		t.Errorf("Expected code 'k', got '%s' (err: %v)", code, err)
+	}
+}
+
+func TestParse_BOMAndCRLF(t *testing.T) {
+	content := "\xef\xbb\xbf# Rime dictionary\r\n---\r\nname: test\r\n...\r\n测试\timya\t10\r\n"
+
+	d, err := Parse(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	if d.Header[0] != "# Rime dictionary" {
+		t.Errorf("BOM was not stripped from the first line: %q", d.Header[0])
+	}
+	if d.Meta.Name != "test" {
+		t.Errorf("Expected name 'test', got %q", d.Meta.Name)
+	}
+	if len(d.Entries) != 1 || d.Entries[0].Word != "测试" || d.Entries[0].Code != "imya" || d.Entries[0].Weight != AbsoluteWeight(10) {
+		t.Fatalf("Unexpected entries: %+v", d.Entries)
+	}
+
+	// Unchanged and changed lines both keep the BOM and CRLF line endings.
+	d.AddOrUpdate("测试", "imya", AbsoluteWeight(20), "")
+	var buf bytes.Buffer
+	if _, err := d.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	expected := "\xef\xbb\xbf# Rime dictionary\r\n---\r\nname: test\r\n...\r\n测试\timya\t20\r\n"
+	if buf.String() != expected {
+		t.Errorf("WriteTo() mismatch:\ngot:  %q\nwant: %q", buf.String(), expected)
+	}
+}
+
+func TestParse_MixedLineEndings(t *testing.T) {
+	content := "# comment\n---\r\nname: test\r\n...\r\n一\tg\t1\n二\tfg\t2\r\n\n三\tdg\t3\r\n"
+
+	d, err := Parse(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	var buf bytes.Buffer
+	d.WriteTo(&buf)
+	if buf.String() != content {
+		t.Errorf("Round trip changed the line endings:\ngot:  %q\nwant: %q", buf.String(), content)
+	}
+
+	// Changed and new lines use the more common ending, the others keep
+	// their own.
+	d.Meta.Version = "1"
+	d.AddOrUpdate("二", "fg", AbsoluteWeight(20), "")
+	d.AddOrUpdate("四", "lh", AbsoluteWeight(4), "新")
+	buf.Reset()
+	d.WriteTo(&buf)
+	expected := "# comment\n---\r\nname: test\r\nversion: \"1\"\r\n...\r\n一\tg\t1\n二\tfg\t20\r\n\n三\tdg\t3\r\n## 新\r\n四\tlh\t4\r\n"
+	if buf.String() != expected {
+		t.Errorf("WriteTo() mismatch:\ngot:  %q\nwant: %q", buf.String(), expected)
+	}
+}
+
+func TestParse_NoHeader(t *testing.T) {
+	content := "# main table\n\n中\tkhk\n国\tlgyi\n"
+
+	d, err := Parse(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	if len(d.Header) != 0 {
+		t.Errorf("Expected no header, got %q", d.Header)
+	}
+	if len(d.Entries) != 4 || !d.Entries[0].IsComment || d.Entries[2].Word != "中" || d.Entries[3].Code != "lgyi" {
+		t.Errorf("Unexpected entries: %+v", d.Entries)
+	}
+
+	var buf bytes.Buffer
+	d.WriteTo(&buf)
+	if buf.String() != content {
+		t.Errorf("WriteTo() mismatch:\ngot:  %q\nwant: %q", buf.String(), content)
+	}
+}
+
+func TestParse_HeaderWithoutStart(t *testing.T) {
+	content := "name: x\ncolumns: [text, weight]\n...\n词语\t10\n"
+
+	d, err := Parse(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	if len(d.Header) != 3 || d.Meta.Name != "x" {
+		t.Errorf("Expected a header named x, got %q", d.Header)
+	}
+	if len(d.Entries) != 1 || d.Entries[0].Word != "词语" || d.Entries[0].Code != "" || d.Entries[0].Weight != AbsoluteWeight(10) {
+		t.Fatalf("Unexpected entries: %+v", d.Entries)
+	}
+
+	d.Meta.Version = "2"
+	var buf bytes.Buffer
+	if _, err := d.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	expected := "name: x\ncolumns: [text, weight]\nversion: \"2\"\n...\n词语\t10\n"
+	if buf.String() != expected {
+		t.Errorf("WriteTo() mismatch:\ngot:  %q\nwant: %q", buf.String(), expected)
+	}
+}
+
+func TestParse_UnterminatedHeader(t *testing.T) {
+	content := "# comment\n---\nname: test\n中\tkhk\n"
+
+	_, err := Parse(strings.NewReader(content))
+	if err == nil {
+		t.Fatal("Parse() should fail when the header has no '...' line")
+	}
+	if !strings.Contains(err.Error(), "line 2") || !strings.Contains(err.Error(), "'...'") {
+		t.Errorf("Error should point at the header start, got: %v", err)
+	}
+}
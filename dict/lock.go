@@ -0,0 +1,107 @@
+package dict
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrLockTimeout is returned when a dictionary lock could not be taken
+// within the wait timeout.
+var ErrLockTimeout = errors.New("timed out waiting for the dictionary lock")
+
+// ErrLockUnsupported is returned on systems without flock, where
+// dictionaries cannot be locked against other processes.
+var ErrLockUnsupported = errors.New("file locks are not supported on this system")
+
+// lockRetryInterval is how often a busy lock is retried.
+const lockRetryInterval = 50 * time.Millisecond
+
+// FileLock is an advisory lock on a dictionary file, held on a separate
+// file in the .rime-dict-manager directory next to it, so that nothing
+// is left behind where Rime or its sync could pick it up.
+type FileLock struct {
+	file *os.File
+}
+
+// LockFile takes an exclusive lock on the dictionary file at path, waiting
+// up to timeout for other processes to release it.
+func LockFile(path string, timeout time.Duration) (*FileLock, error) {
+	// Lock the real file, so a symlink and its target share one lock.
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+	lockPath := LockPath(path)
+
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+	file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		locked, err := tryLock(file)
+		if errors.Is(err, ErrLockUnsupported) {
+			file.Close()
+			return nil, err
+		}
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to lock '%s': %w", lockPath, err)
+		}
+		if locked {
+			return &FileLock{file: file}, nil
+		}
+		if !time.Now().Before(deadline) {
+			file.Close()
+			return nil, fmt.Errorf("%w: '%s' is held by another process (waited %s)", ErrLockTimeout, lockPath, timeout)
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+// LockPath returns the lock file of the dictionary file at path.
+func LockPath(path string) string {
+	return filepath.Join(filepath.Dir(path), ".rime-dict-manager", filepath.Base(path)+".lock")
+}
+
+// Unlock releases the lock.
+func (l *FileLock) Unlock() error {
+	if err := unlock(l.file); err != nil {
+		l.file.Close()
+		return err
+	}
+	return l.file.Close()
+}
+
+// Lock takes the dictionary's file lock, waiting up to timeout. Take it
+// before Load and release it with Unlock after Save, so no other process
+// can change the file in between. It returns ErrLockUnsupported on
+// systems that cannot lock files.
+func (d *Dictionary) Lock(timeout time.Duration) error {
+	if d.lock != nil {
+		return nil
+	}
+	lock, err := LockFile(d.path, timeout)
+	if err != nil {
+		return err
+	}
+	d.lock = lock
+	return nil
+}
+
+// Unlock releases the lock taken by Lock. It is a no-op if the dictionary
+// is not locked.
+func (d *Dictionary) Unlock() error {
+	if d.lock == nil {
+		return nil
+	}
+	err := d.lock.Unlock()
+	d.lock = nil
+	return err
+}
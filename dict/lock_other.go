@@ -0,0 +1,15 @@
+//go:build !unix
+
+package dict
+
+import "os"
+
+// tryLock fails with ErrLockUnsupported: advisory locks are only
+// implemented with flock on Unix systems.
+func tryLock(file *os.File) (bool, error) {
+	return false, ErrLockUnsupported
+}
+
+func unlock(file *os.File) error {
+	return nil
+}
@@ -0,0 +1,46 @@
+//go:build unix
+
+package dict
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLockFile(t *testing.T) {
+	dictPath := createTempDictFile(t, t.TempDir(), "---\n...\n")
+
+	lock, err := LockFile(dictPath, time.Second)
+	if err != nil {
+		t.Fatalf("LockFile() failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dictPath), ".rime-dict-manager", filepath.Base(dictPath)+".lock")); err != nil {
+		t.Errorf("Expected the lock file in .rime-dict-manager: %v", err)
+	}
+	if _, err := os.Stat(dictPath + ".lock"); err == nil {
+		t.Error("No lock file should be left next to the dictionary")
+	}
+
+	d := NewDictionary(dictPath)
+	start := time.Now()
+	err = d.Lock(100 * time.Millisecond)
+	if !errors.Is(err, ErrLockTimeout) {
+		t.Fatalf("Expected ErrLockTimeout while the lock is held, got %v", err)
+	}
+	if time.Since(start) < 100*time.Millisecond {
+		t.Errorf("Lock() gave up before the timeout")
+	}
+
+	if err := lock.Unlock(); err != nil {
+		t.Fatalf("Unlock() failed: %v", err)
+	}
+	if err := d.Lock(100 * time.Millisecond); err != nil {
+		t.Fatalf("Lock() failed after the lock was released: %v", err)
+	}
+	if err := d.Unlock(); err != nil {
+		t.Errorf("Unlock() failed: %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+package dict
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SourcedEntry is a word entry of a MergedView together with the file it
+// was read from.
+type SourcedEntry struct {
+	Entry  Entry
+	Source string // Path of the dictionary file holding the entry
+	Group  string // The '##' group the entry is listed under, if any
+}
+
+// MergedView is a read-only view of one or more dictionaries and every
+// table they pull in through import_tables, as Rime sees them.
+type MergedView struct {
+	Root    *Dictionary // The first dictionary passed to LoadMerged
+	Sources []string    // Paths of all loaded files, in load order
+	Entries []SourcedEntry
+
+	fingerprints []Fingerprint // The loaded files as they were read
+}
+
+// LoadMerged loads the dictionaries at paths and, recursively, the tables
+// listed in their import_tables. An imported table named "x" is looked up
+// as "x.dict.yaml" in rimeDir, then next to the file importing it. Each
+// file is loaded only once, so import cycles are harmless.
+func LoadMerged(rimeDir string, paths ...string) (*MergedView, error) {
+	v := &MergedView{}
+	visited := make(map[string]bool)
+	for _, path := range paths {
+		if err := v.load(path, rimeDir, visited); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+func (v *MergedView) load(path, rimeDir string, visited map[string]bool) error {
+	key := path
+	if abs, err := filepath.Abs(path); err == nil {
+		key = abs
+	}
+	if visited[key] {
+		return nil
+	}
+	visited[key] = true
+
+	d := NewDictionary(path)
+	if err := d.Load(); err != nil {
+		return fmt.Errorf("failed to load '%s': %w", path, err)
+	}
+	if v.Root == nil {
+		v.Root = d
+	}
+	v.Sources = append(v.Sources, path)
+	v.fingerprints = append(v.fingerprints, *d.loaded)
+
+	group := ""
+	for _, entry := range d.Entries {
+		if entry.IsGroup {
+			group = entry.Group
+			continue
+		}
+		if entry.IsComment || entry.Word == "" {
+			continue
+		}
+		v.Entries = append(v.Entries, SourcedEntry{Entry: entry, Source: path, Group: group})
+	}
+
+	for _, name := range d.Meta.ImportTables {
+		importPath, err := resolveImport(name, path, rimeDir)
+		if err != nil {
+			return err
+		}
+		if err := v.load(importPath, rimeDir, visited); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveImport finds the file of an import_tables item.
+func resolveImport(name, importer, rimeDir string) (string, error) {
+	file := name + ".dict.yaml"
+	var tried []string
+	for _, dir := range []string{rimeDir, filepath.Dir(importer)} {
+		if dir == "" {
+			continue
+		}
+		candidate := filepath.Join(dir, file)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+		tried = append(tried, candidate)
+	}
+	return "", fmt.Errorf("import table '%s' of '%s' not found (tried %s)", name, importer, strings.Join(tried, ", "))
+}
+
+// Lookup returns every entry for word, in load order.
+func (v *MergedView) Lookup(word string) []SourcedEntry {
+	var found []SourcedEntry
+	for _, e := range v.Entries {
+		if e.Entry.Word == word {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+// Flatten returns a standalone dictionary at path holding every entry of
+// the view. The root dictionary's header is kept, except that
+// import_tables is dropped and the name is set to name, as Rime requires
+// it to match the file name.
+func (v *MergedView) Flatten(path, name string) *Dictionary {
+	d := NewDictionary(path)
+	if v.Root != nil {
+		d.Header = append([]string(nil), v.Root.Header...)
+		d.Meta = v.Root.Meta
+		d.hasBOM = v.Root.hasBOM
+		d.lineEnding = v.Root.lineEnding
+	}
+	d.Meta.Name = name
+	d.Meta.ImportTables = nil
+
+	source, group := "", ""
+	for _, e := range v.Entries {
+		if e.Source != source {
+			source, group = e.Source, ""
+			d.Entries = append(d.Entries, Entry{IsComment: true, Comment: "# " + filepath.Base(source)})
+		}
+		if e.Group != group {
+			group = e.Group
+			d.Entries = append(d.Entries, Entry{IsGroup: true, Group: group})
+		}
+		entry := e.Entry
+		// Imported tables may declare other columns, so render every
+		// entry again with the root's columns.
+		entry.Dirty = true
+		d.Entries = append(d.Entries, entry)
+	}
+	return d
+}
@@ -0,0 +1,107 @@
+package dict
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeDictFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+}
+
+func TestLoadMerged(t *testing.T) {
+	dir := t.TempDir()
+	writeDictFiles(t, dir, map[string]string{
+		"main.dict.yaml": "---\nname: main\nimport_tables:\n  - user\n  - extra\n...\n中\tkhk\n",
+		"user.dict.yaml": "---\nname: user\n...\n## Work\n用例\tetwg\t200\n",
+		// The extra table uses other columns and imports main again.
+		"extra.dict.yaml": "---\nname: extra\ncolumns: [text, weight, code]\nimport_tables: [main]\n...\n中\t5\tk\n",
+	})
+
+	v, err := LoadMerged(dir, filepath.Join(dir, "main.dict.yaml"))
+	if err != nil {
+		t.Fatalf("LoadMerged() failed: %v", err)
+	}
+
+	if len(v.Sources) != 3 {
+		t.Fatalf("Expected 3 sources, got %v", v.Sources)
+	}
+	if v.Root.Meta.Name != "main" {
+		t.Errorf("Expected root 'main', got '%s'", v.Root.Meta.Name)
+	}
+
+	found := v.Lookup("中")
+	if len(found) != 2 {
+		t.Fatalf("Expected 2 entries for 中, got %+v", found)
+	}
+	if found[0].Entry.Code != "khk" || filepath.Base(found[0].Source) != "main.dict.yaml" {
+		t.Errorf("Unexpected first entry: %+v", found[0])
+	}
+	if found[1].Entry.Code != "k" || found[1].Entry.Weight != AbsoluteWeight(5) || filepath.Base(found[1].Source) != "extra.dict.yaml" {
+		t.Errorf("Unexpected second entry: %+v", found[1])
+	}
+
+	found = v.Lookup("用例")
+	if len(found) != 1 || found[0].Group != "Work" {
+		t.Errorf("Expected 用例 in group Work, got %+v", found)
+	}
+}
+
+func TestLoadMerged_MissingImport(t *testing.T) {
+	dir := t.TempDir()
+	writeDictFiles(t, dir, map[string]string{
+		"main.dict.yaml": "---\nname: main\nimport_tables: [missing]\n...\n",
+	})
+
+	_, err := LoadMerged(dir, filepath.Join(dir, "main.dict.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("Expected an error naming the missing table, got %v", err)
+	}
+}
+
+func TestLoadMerged_ImportNextToImporter(t *testing.T) {
+	rimeDir := t.TempDir()
+	otherDir := t.TempDir()
+	writeDictFiles(t, otherDir, map[string]string{
+		"main.dict.yaml":  "---\nname: main\nimport_tables: [local]\n...\n",
+		"local.dict.yaml": "---\nname: local\n...\n国\tlgyi\n",
+	})
+
+	v, err := LoadMerged(rimeDir, filepath.Join(otherDir, "main.dict.yaml"))
+	if err != nil {
+		t.Fatalf("LoadMerged() failed: %v", err)
+	}
+	if len(v.Lookup("国")) != 1 {
+		t.Errorf("Expected the table next to the importer to be loaded, sources: %v", v.Sources)
+	}
+}
+
+func TestMergedView_Flatten(t *testing.T) {
+	dir := t.TempDir()
+	writeDictFiles(t, dir, map[string]string{
+		"main.dict.yaml":  "# main\n---\nname: main\nversion: \"1\"\nimport_tables:\n  - extra\n...\n中\tkhk\t10\n",
+		"extra.dict.yaml": "---\nname: extra\ncolumns: [text, weight, code]\n...\n## Extra\n国\t5\tlgyi\n",
+	})
+
+	v, err := LoadMerged(dir, filepath.Join(dir, "main.dict.yaml"))
+	if err != nil {
+		t.Fatalf("LoadMerged() failed: %v", err)
+	}
+
+	output := filepath.Join(dir, "flat.dict.yaml")
+	if err := v.Flatten(output, "flat").Save(); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	expected := "# main\n---\nname: flat\nversion: \"1\"\n...\n# main.dict.yaml\n中\tkhk\t10\n# extra.dict.yaml\n## Extra\n国\tlgyi\t5\n"
+	if got := readFile(t, output); got != expected {
+		t.Errorf("Flattened content mismatch:\ngot:\n%s\nwant:\n%s", got, expected)
+	}
+}
@@ -0,0 +1,384 @@
+package dict
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Metadata holds the well-known fields of a dictionary's YAML header.
+// Keys that are not listed here, and any comments, stay untouched in
+// Dictionary.Header.
+type Metadata struct {
+	Name                string
+	Version             string
+	Sort                string
+	Columns             []string
+	UsePresetVocabulary bool
+	MaxPhraseLength     int
+	ImportTables        []string
+}
+
+// MetadataKeys lists the header keys understood by Metadata, in the order
+// they usually appear in a Rime dictionary.
+var MetadataKeys = []string{
+	"name",
+	"version",
+	"sort",
+	"columns",
+	"use_preset_vocabulary",
+	"max_phrase_length",
+	"import_tables",
+}
+
+var headerKeyRe = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*):(.*)$`)
+
+// Get returns the value of a metadata key formatted for display.
+// List values are joined with ", ".
+func (m *Metadata) Get(key string) (string, error) {
+	switch key {
+	case "name":
+		return m.Name, nil
+	case "version":
+		return m.Version, nil
+	case "sort":
+		return m.Sort, nil
+	case "columns":
+		return strings.Join(m.Columns, ", "), nil
+	case "use_preset_vocabulary":
+		return strconv.FormatBool(m.UsePresetVocabulary), nil
+	case "max_phrase_length":
+		if m.MaxPhraseLength == 0 {
+			return "", nil
+		}
+		return strconv.Itoa(m.MaxPhraseLength), nil
+	case "import_tables":
+		return strings.Join(m.ImportTables, ", "), nil
+	}
+	return "", fmt.Errorf("unknown metadata key '%s'", key)
+}
+
+// Set parses value and assigns it to the metadata key. List values are
+// comma separated. An empty value removes the key from the header on Save,
+// except use_preset_vocabulary, for which it means false.
+func (m *Metadata) Set(key, value string) error {
+	value = strings.TrimSpace(value)
+	switch key {
+	case "name":
+		m.Name = value
+	case "version":
+		m.Version = value
+	case "sort":
+		if value != "" && value != "by_weight" && value != "original" {
+			return fmt.Errorf("invalid sort '%s': must be by_weight or original", value)
+		}
+		m.Sort = value
+	case "columns":
+		m.Columns = splitList(value)
+	case "use_preset_vocabulary":
+		if value == "" {
+			m.UsePresetVocabulary = false
+			return nil
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid use_preset_vocabulary '%s': must be true or false", value)
+		}
+		m.UsePresetVocabulary = b
+	case "max_phrase_length":
+		if value == "" {
+			m.MaxPhraseLength = 0
+			return nil
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid max_phrase_length '%s': must be a non-negative integer", value)
+		}
+		m.MaxPhraseLength = n
+	case "import_tables":
+		m.ImportTables = splitList(value)
+	default:
+		return fmt.Errorf("unknown metadata key '%s'", key)
+	}
+	return nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// headerField is a top-level key found in the header, spanning the header
+// lines [start, end).
+type headerField struct {
+	key        string
+	value      string   // scalar value, or the raw flow sequence
+	items      []string // sequence items
+	start, end int
+}
+
+// headerBody returns the indexes of the '---' and '...' lines, or -1 if
+// they are missing. A header may leave out the '---' line and start
+// directly with its first key.
+func headerBody(header []string) (begin, end int) {
+	begin, end = -1, -1
+	for i, line := range header {
+		if begin < 0 && strings.HasPrefix(line, "---") {
+			begin = i
+		} else if strings.HasPrefix(line, "...") {
+			end = i
+			break
+		}
+	}
+	return begin, end
+}
+
+// scanHeader finds the top-level keys of the YAML header.
+func scanHeader(header []string) []headerField {
+	begin, end := headerBody(header)
+	if begin < 0 && end < 0 {
+		return nil
+	}
+	if end < 0 {
+		end = len(header)
+	}
+
+	var fields []headerField
+	for i := begin + 1; i < end; i++ {
+		m := headerKeyRe.FindStringSubmatch(header[i])
+		if m == nil {
+			continue
+		}
+		f := headerField{key: m[1], start: i, end: i + 1}
+		f.value = unquoteScalar(m[2])
+		if f.value == "" {
+			// The value may be a block sequence on the following lines.
+			// Indented comments inside the block belong to it.
+			for j := i + 1; j < end; j++ {
+				trimmed := strings.TrimSpace(header[j])
+				if strings.HasPrefix(trimmed, "- ") || trimmed == "-" {
+					f.items = append(f.items, unquoteScalar(strings.TrimPrefix(trimmed, "-")))
+					f.end = j + 1
+				} else if trimmed != "" && strings.HasPrefix(header[j], " ") && strings.HasPrefix(trimmed, "#") {
+					continue
+				} else {
+					break
+				}
+			}
+		} else if strings.HasPrefix(f.value, "[") {
+			f.items = splitFlowSequence(f.value)
+		}
+		fields = append(fields, f)
+		i = f.end - 1
+	}
+	return fields
+}
+
+// unquoteScalar strips surrounding quotes and trailing comments from a
+// YAML scalar.
+func unquoteScalar(s string) string {
+	s = strings.TrimSpace(s)
+	switch {
+	case strings.HasPrefix(s, `"`):
+		for i := 1; i < len(s); i++ {
+			if s[i] == '\\' {
+				i++
+			} else if s[i] == '"' {
+				if v, err := strconv.Unquote(s[:i+1]); err == nil {
+					return v
+				}
+				return s[1:i]
+			}
+		}
+		return s
+	case strings.HasPrefix(s, "'"):
+		var b strings.Builder
+		for i := 1; i < len(s); i++ {
+			if s[i] == '\'' {
+				if i+1 < len(s) && s[i+1] == '\'' {
+					b.WriteByte('\'')
+					i++
+					continue
+				}
+				return b.String()
+			}
+			b.WriteByte(s[i])
+		}
+		return s
+	case strings.HasPrefix(s, "["):
+		if i := strings.Index(s, "]"); i >= 0 {
+			return s[:i+1]
+		}
+		return s
+	}
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = s[:i]
+	} else if strings.HasPrefix(s, "#") {
+		return ""
+	}
+	return strings.TrimSpace(s)
+}
+
+func splitFlowSequence(s string) []string {
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = unquoteScalar(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseMetadata extracts the well-known keys from header lines.
+func parseMetadata(header []string) (Metadata, error) {
+	var m Metadata
+	for _, f := range scanHeader(header) {
+		var err error
+		switch f.key {
+		case "columns":
+			m.Columns = f.items
+		case "import_tables":
+			m.ImportTables = f.items
+		case "name", "version", "sort", "use_preset_vocabulary", "max_phrase_length":
+			err = m.Set(f.key, f.value)
+		}
+		if err != nil {
+			return m, fmt.Errorf("invalid dictionary header: %w", err)
+		}
+	}
+	return m, nil
+}
+
+// applyMetadata returns header with the keys whose value differs from m
+// rewritten. Lines of unchanged keys, unknown keys and comments are kept
+// as they are.
+func applyMetadata(header []string, m Metadata) []string {
+	current, _ := parseMetadata(header)
+
+	type edit struct {
+		start, end int
+		lines      []string
+	}
+	var edits []edit
+	fields := make(map[string]headerField)
+	for _, f := range scanHeader(header) {
+		fields[f.key] = f
+	}
+	_, end := headerBody(header)
+	for _, key := range MetadataKeys {
+		have, _ := current.Get(key)
+		want, _ := m.Get(key)
+		if have == want {
+			continue
+		}
+		f, ok := fields[key]
+		lines := m.render(key, ok)
+		if !ok {
+			edits = append(edits, edit{start: end, end: end, lines: lines})
+			continue
+		}
+		// Keep comments that lived inside the old block.
+		for i := f.start + 1; i < f.end; i++ {
+			if strings.HasPrefix(strings.TrimSpace(header[i]), "#") {
+				lines = append(lines, header[i])
+			}
+		}
+		edits = append(edits, edit{start: f.start, end: f.end, lines: lines})
+	}
+	if len(edits) == 0 {
+		return header
+	}
+
+	if end < 0 {
+		// There is no header block yet, or it is not terminated.
+		begin, _ := headerBody(header)
+		if begin < 0 {
+			header = append(header, "---")
+		}
+		end = len(header)
+		header = append(header, "...")
+		for i := range edits {
+			if edits[i].start < 0 {
+				edits[i].start, edits[i].end = end, end
+			}
+		}
+	}
+
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+	result := make([]string, 0, len(header)+len(edits))
+	pos := 0
+	for _, e := range edits {
+		result = append(result, header[pos:e.start]...)
+		result = append(result, e.lines...)
+		pos = e.end
+	}
+	return append(result, header[pos:]...)
+}
+
+// render formats a metadata key as header lines, or returns nil if the
+// key has no value. present tells whether the header already has the key,
+// in which case use_preset_vocabulary is written even when false.
+func (m *Metadata) render(key string, present bool) []string {
+	var list []string
+	switch key {
+	case "columns":
+		list = m.Columns
+	case "import_tables":
+		list = m.ImportTables
+	case "use_preset_vocabulary":
+		if !m.UsePresetVocabulary && !present {
+			return nil
+		}
+		return []string{"use_preset_vocabulary: " + strconv.FormatBool(m.UsePresetVocabulary)}
+	case "max_phrase_length":
+		if m.MaxPhraseLength == 0 {
+			return nil
+		}
+		// An integer, so not quoted like other scalars that look numeric.
+		return []string{"max_phrase_length: " + strconv.Itoa(m.MaxPhraseLength)}
+	case "version":
+		if m.Version == "" {
+			return nil
+		}
+		// Rime dictionaries conventionally quote the version.
+		return []string{"version: " + strconv.Quote(m.Version)}
+	default:
+		value, _ := m.Get(key)
+		if value == "" {
+			return nil
+		}
+		return []string{key + ": " + quoteScalar(value)}
+	}
+	if len(list) == 0 {
+		return nil
+	}
+	lines := []string{key + ":"}
+	for _, item := range list {
+		lines = append(lines, "  - "+quoteScalar(item))
+	}
+	return lines
+}
+
+// quoteScalar quotes s if YAML would otherwise read it as something other
+// than a plain string.
+func quoteScalar(s string) string {
+	if s == "" || strings.TrimSpace(s) != s || strings.ContainsAny(s, ":#[]{},&*!|>'\"%@`") {
+		return strconv.Quote(s)
+	}
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "null", "~":
+		return strconv.Quote(s)
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,153 @@
+package dict
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const metaTestHeader = `# Rime dictionary
+# encoding: utf-8
+---
+name: wubi86_jidian
+version: "2025.01.01" # bumped by hand
+sort: by_weight
+columns:
+  - text
+  - code
+  - weight
+encoder:
+  exclude_patterns:
+    - '^z.*$'
+use_preset_vocabulary: false
+max_phrase_length: 8
+import_tables:
+  - wubi86_jidian_user
+  # extra words
+  - wubi86_jidian_extra
+...`
+
+func TestParseMetadata(t *testing.T) {
+	header := strings.Split(metaTestHeader, "\n")
+
+	m, err := parseMetadata(header)
+	if err != nil {
+		t.Fatalf("parseMetadata() failed: %v", err)
+	}
+
+	expected := Metadata{
+		Name:            "wubi86_jidian",
+		Version:         "2025.01.01",
+		Sort:            "by_weight",
+		Columns:         []string{"text", "code", "weight"},
+		MaxPhraseLength: 8,
+		ImportTables:    []string{"wubi86_jidian_user", "wubi86_jidian_extra"},
+	}
+	if !reflect.DeepEqual(m, expected) {
+		t.Errorf("Metadata mismatch:\ngot:  %+v\nwant: %+v", m, expected)
+	}
+}
+
+func TestParseMetadata_FlowSequence(t *testing.T) {
+	header := []string{"---", "name: test", "columns: [text, 'weight']", "use_preset_vocabulary: true", "..."}
+
+	m, err := parseMetadata(header)
+	if err != nil {
+		t.Fatalf("parseMetadata() failed: %v", err)
+	}
+	if !reflect.DeepEqual(m.Columns, []string{"text", "weight"}) || !m.UsePresetVocabulary {
+		t.Errorf("Unexpected metadata: %+v", m)
+	}
+}
+
+func TestParseMetadata_Invalid(t *testing.T) {
+	header := []string{"---", "max_phrase_length: many", "..."}
+	if _, err := parseMetadata(header); err == nil {
+		t.Error("Expected an error for a non-numeric max_phrase_length")
+	}
+}
+
+func TestApplyMetadata(t *testing.T) {
+	header := strings.Split(metaTestHeader, "\n")
+
+	// An unchanged Metadata must not touch the header.
+	m, _ := parseMetadata(header)
+	if got := applyMetadata(header, m); !reflect.DeepEqual(got, header) {
+		t.Errorf("Unchanged metadata rewrote the header:\n%s", strings.Join(got, "\n"))
+	}
+
+	if err := m.Set("version", "2026.10.16"); err != nil {
+		t.Fatalf("Set() failed: %v", err)
+	}
+	if err := m.Set("import_tables", "wubi86_jidian_user"); err != nil {
+		t.Fatalf("Set() failed: %v", err)
+	}
+	m.MaxPhraseLength = 0
+
+	got := strings.Join(applyMetadata(header, m), "\n")
+	expected := `# Rime dictionary
+# encoding: utf-8
+---
+name: wubi86_jidian
+version: "2026.10.16"
+sort: by_weight
+columns:
+  - text
+  - code
+  - weight
+encoder:
+  exclude_patterns:
+    - '^z.*$'
+use_preset_vocabulary: false
+import_tables:
+  - wubi86_jidian_user
+  # extra words
+...`
+	if got != expected {
+		t.Errorf("Header mismatch:\ngot:\n%s\nwant:\n%s", got, expected)
+	}
+}
+
+func TestApplyMetadata_NewHeader(t *testing.T) {
+	m := Metadata{Name: "test", Sort: "original"}
+
+	got := applyMetadata(nil, m)
+	expected := []string{"---", "name: test", "sort: original", "..."}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Header mismatch:\ngot:  %q\nwant: %q", got, expected)
+	}
+}
+
+func TestApplyMetadata_Scalars(t *testing.T) {
+	header := []string{"---", "name: test", "use_preset_vocabulary: true", "..."}
+	m, _ := parseMetadata(header)
+	m.UsePresetVocabulary = false
+	m.MaxPhraseLength = 5
+
+	// A key the user wrote stays when it becomes false, and an integer is
+	// not quoted as a string.
+	got := applyMetadata(header, m)
+	expected := []string{"---", "name: test", "use_preset_vocabulary: false", "max_phrase_length: 5", "..."}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Header mismatch:\ngot:  %q\nwant: %q", got, expected)
+	}
+	if m2, _ := parseMetadata(got); m2.MaxPhraseLength != 5 || m2.UsePresetVocabulary {
+		t.Errorf("Unexpected metadata after rewriting: %+v", m2)
+	}
+}
+
+func TestMetadata_Set(t *testing.T) {
+	var m Metadata
+	if err := m.Set("sort", "random"); err == nil {
+		t.Error("Expected an error for an invalid sort value")
+	}
+	if err := m.Set("unknown", "x"); err == nil {
+		t.Error("Expected an error for an unknown key")
+	}
+	if err := m.Set("columns", "text, code, stem"); err != nil {
+		t.Fatalf("Set() failed: %v", err)
+	}
+	if value, _ := m.Get("columns"); value != "text, code, stem" {
+		t.Errorf("Expected columns 'text, code, stem', got '%s'", value)
+	}
+}
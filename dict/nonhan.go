@@ -0,0 +1,196 @@
+package dict
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// NonHanPolicy tells an encoder what to do with the characters of a word
+// that are not Chinese, such as the Latin letters and digits of "A股" or
+// "3D打印".
+type NonHanPolicy int
+
+const (
+	NonHanReject NonHanPolicy = iota // Fail on such words
+	NonHanSkip                       // Encode the word without them
+	NonHanLetter                     // Use a lowercase letter or digit as its own code
+)
+
+// NonHanPolicies lists the policy names ParseNonHanPolicy accepts.
+var NonHanPolicies = []string{"reject", "skip", "letter"}
+
+// ParseNonHanPolicy parses a policy name.
+func ParseNonHanPolicy(s string) (NonHanPolicy, error) {
+	for i, name := range NonHanPolicies {
+		if s == name {
+			return NonHanPolicy(i), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid policy '%s': must be one of %s", s, strings.Join(NonHanPolicies, ", "))
+}
+
+func (p NonHanPolicy) String() string {
+	if p >= 0 && int(p) < len(NonHanPolicies) {
+		return NonHanPolicies[p]
+	}
+	return fmt.Sprintf("NonHanPolicy(%d)", int(p))
+}
+
+// NormalizeWidth maps the full-width forms of ASCII characters, such as
+// "Ａ" or "３", to ASCII, and the ideographic space to a space.
+func NormalizeWidth(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= '！' && r <= '～':
+			return r - 0xFEE0
+		case r == '　':
+			return ' '
+		}
+		return r
+	}, s)
+}
+
+// NonHanEncoder applies a NonHanPolicy before handing words to another
+// encoder. Words are normalized with NormalizeWidth first.
+type NonHanEncoder struct {
+	Encoder Encoder
+	Policy  NonHanPolicy
+}
+
+// syllabicEncoder is implemented by encoders whose codes are syllables
+// separated by spaces.
+type syllabicEncoder interface {
+	syllabic()
+}
+
+func (e *PinyinEncoder) syllabic()    {}
+func (e *ShuangpinEncoder) syllabic() {}
+
+// learnLetters makes a table encoder code the letters and digits by
+// themselves, for NonHanLetter. Encoders of syllables keep them apart
+// instead, see NonHanEncoder.GenerateCode.
+func learnLetters(enc Encoder) {
+	if _, syllabic := enc.(syllabicEncoder); syllabic {
+		return
+	}
+	if o, ok := enc.(charOverrider); ok {
+		for _, r := range "abcdefghijklmnopqrstuvwxyz0123456789" {
+			o.overrideChar(r, []string{string(r)})
+		}
+	}
+}
+
+// GenerateCode generates the code of word. With NonHanLetter, an encoder
+// of syllables gets each letter or digit as a syllable of its own.
+func (e *NonHanEncoder) GenerateCode(word string) (string, error) {
+	runes, err := e.prepare(word)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := e.Encoder.(syllabicEncoder); !ok || e.Policy != NonHanLetter {
+		code, err := e.Encoder.GenerateCode(string(runes))
+		return code, withWord(err, word)
+	}
+
+	// Encode the runs of Chinese characters on their own and put the
+	// letters between them.
+	var segments [][]string
+	var missing []rune
+	for i := 0; i < len(runes); {
+		j := i
+		for j < len(runes) && unicode.Is(unicode.Han, runes[j]) {
+			j++
+		}
+		if j == i {
+			segments = append(segments, []string{string(runes[i])})
+			i++
+			continue
+		}
+		code, err := e.Encoder.GenerateCode(string(runes[i:j]))
+		var ambiguous *AmbiguousCodeError
+		var missingChars *MissingCharError
+		switch {
+		case errors.As(err, &ambiguous):
+			segments = append(segments, ambiguous.Candidates)
+		case errors.As(err, &missingChars):
+			// Keep going to report the characters of every run.
+			for _, r := range missingChars.Chars {
+				if !slices.Contains(missing, r) {
+					missing = append(missing, r)
+				}
+			}
+		case err != nil:
+			return "", err
+		default:
+			segments = append(segments, []string{code})
+		}
+		i = j
+	}
+	if err := newMissingCharError(missing); err != nil {
+		return "", err
+	}
+	candidates := []string{""}
+	for _, options := range segments {
+		var next []string
+		for _, prefix := range candidates {
+			for _, option := range options {
+				next = append(next, strings.TrimPrefix(prefix+" "+option, " "))
+			}
+		}
+		candidates = next
+	}
+	if len(candidates) > 1 {
+		return "", &AmbiguousCodeError{Word: word, Candidates: candidates}
+	}
+	return candidates[0], nil
+}
+
+// Explain explains the code of word if the wrapped encoder can.
+func (e *NonHanEncoder) Explain(word string) (*Explanation, error) {
+	x, ok := e.Encoder.(Explainer)
+	if !ok {
+		return nil, fmt.Errorf("the encoder cannot explain its codes")
+	}
+	runes, err := e.prepare(word)
+	if err != nil {
+		return nil, err
+	}
+	return x.Explain(string(runes))
+}
+
+// prepare applies the policy to the characters of word. With NonHanLetter,
+// letters are lowercased, and other characters such as punctuation and
+// spaces are dropped.
+func (e *NonHanEncoder) prepare(word string) ([]rune, error) {
+	var runes []rune
+	for _, r := range NormalizeWidth(word) {
+		if unicode.Is(unicode.Han, r) {
+			runes = append(runes, r)
+			continue
+		}
+		switch e.Policy {
+		case NonHanReject:
+			return nil, fmt.Errorf("'%s' contains the non-Chinese character '%c'; use the skip or letter policy to encode it", word, r)
+		case NonHanLetter:
+			if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+				runes = append(runes, unicode.ToLower(r))
+			}
+		}
+	}
+	if len(runes) == 0 {
+		return nil, fmt.Errorf("'%s' has no characters to encode", word)
+	}
+	return runes, nil
+}
+
+// withWord makes an ambiguity error name the word as it was given.
+func withWord(err error, word string) error {
+	var ambiguous *AmbiguousCodeError
+	if errors.As(err, &ambiguous) {
+		return &AmbiguousCodeError{Word: word, Candidates: ambiguous.Candidates}
+	}
+	return err
+}
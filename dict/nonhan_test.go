@@ -0,0 +1,89 @@
+package dict
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeWidth(t *testing.T) {
+	if got := NormalizeWidth("Ｋ线图，３Ｄ　打印"); got != "K线图,3D 打印" {
+		t.Errorf("NormalizeWidth = %q", got)
+	}
+}
+
+func TestParseNonHanPolicy(t *testing.T) {
+	for _, name := range NonHanPolicies {
+		p, err := ParseNonHanPolicy(name)
+		if err != nil || p.String() != name {
+			t.Errorf("ParseNonHanPolicy(%s) = %v, %v", name, p, err)
+		}
+	}
+	if _, err := ParseNonHanPolicy("ignore"); err == nil {
+		t.Error("ParseNonHanPolicy should reject an unknown policy")
+	}
+}
+
+func TestNonHanEncoder(t *testing.T) {
+	wubiView, _ := LoadMerged("", wubiSampleDict)
+	pinyinView, _ := LoadMerged("", pinyinSampleDict)
+
+	testCases := []struct {
+		scheme   string
+		policy   NonHanPolicy
+		word     string
+		expected string
+	}{
+		{"wubi86", NonHanSkip, "Ａ中国", "khlg"},
+		{"wubi86", NonHanLetter, "A中国", "aklg"},
+		{"wubi86", NonHanLetter, "Ｋ中国", "kklg"},
+		{"wubi86", NonHanLetter, "3D中国", "3dkl"},
+		// The dash is dropped, and A has no second letter for Cb.
+		{"wubi86", NonHanLetter, "中国-A", "kla"},
+		{"pinyin", NonHanSkip, "K银行", "yin hang"},
+		{"pinyin", NonHanLetter, "K银行", "k yin hang"},
+		{"pinyin", NonHanLetter, "银行3D", "yin hang 3 d"},
+		{"shuangpin-xiaohe", NonHanLetter, "K银行", "k yb hh"},
+	}
+	for _, tc := range testCases {
+		view := wubiView
+		if tc.scheme != "wubi86" {
+			view = pinyinView
+		}
+		encoder, err := NewEncoder(tc.scheme, EncoderOptions{Main: view, NonHan: tc.policy})
+		if err != nil {
+			t.Fatalf("NewEncoder(%s) failed: %v", tc.scheme, err)
+		}
+		code, err := encoder.GenerateCode(tc.word)
+		if err != nil {
+			t.Errorf("%s/%s: GenerateCode(%s) failed: %v", tc.scheme, tc.policy, tc.word, err)
+			continue
+		}
+		if code != tc.expected {
+			t.Errorf("%s/%s: GenerateCode(%s) = %s, want %s", tc.scheme, tc.policy, tc.word, code, tc.expected)
+		}
+	}
+}
+
+func TestNonHanEncoder_RejectAndAmbiguous(t *testing.T) {
+	wubiView, _ := LoadMerged("", wubiSampleDict)
+	encoder, _ := NewEncoder("wubi86", EncoderOptions{Main: wubiView})
+	if _, err := encoder.GenerateCode("A中国"); err == nil {
+		t.Error("The reject policy should refuse 'A中国'")
+	}
+	skip, _ := NewEncoder("wubi86", EncoderOptions{Main: wubiView, NonHan: NonHanSkip})
+	if _, err := skip.GenerateCode("ABC"); err == nil {
+		t.Error("A word with nothing left to encode should fail")
+	}
+
+	pinyinView, _ := LoadMerged("", pinyinSampleDict)
+	encoder, _ = NewEncoder("pinyin", EncoderOptions{Main: pinyinView, NonHan: NonHanLetter})
+	_, err := encoder.GenerateCode("A重")
+	var ambiguous *AmbiguousCodeError
+	if !errors.As(err, &ambiguous) {
+		t.Fatalf("GenerateCode(A重) error = %v, want an *AmbiguousCodeError", err)
+	}
+	if expected := []string{"a chong", "a zhong"}; ambiguous.Word != "A重" || !reflect.DeepEqual(ambiguous.Candidates, expected) {
+		t.Errorf("Ambiguity = %+v, want candidates %v", ambiguous, expected)
+	}
+}
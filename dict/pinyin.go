@@ -0,0 +1,141 @@
+package dict
+
+import (
+	"slices"
+	"sort"
+	"strings"
+)
+
+// PinyinEncoder generates full pinyin codes, syllables separated by
+// spaces, as used by luna_pinyin and similar schemas. The reading of a
+// polyphonic character is taken from the longest phrase of the main
+// dictionary that covers it.
+type PinyinEncoder struct {
+	readings  map[rune][]string // Readings of each character, in file order
+	phrases   map[string]pinyinPhrase
+	maxPhrase int // Length of the longest phrase, in characters
+}
+
+type pinyinPhrase struct {
+	syllables []string
+	weight    Weight
+}
+
+// NewPinyinEncoderFromView creates an encoder from the entries of a
+// merged pinyin dictionary view. Readings with a weight of 0 are rare
+// ones Rime never offers, so they are ignored unless a character has no
+// other reading.
+func NewPinyinEncoderFromView(v *MergedView) *PinyinEncoder {
+	e := &PinyinEncoder{
+		readings: make(map[rune][]string),
+		phrases:  make(map[string]pinyinPhrase),
+	}
+	rare := make(map[rune][]string)
+	for _, se := range v.Entries {
+		word, code := se.Entry.Word, strings.Join(strings.Fields(se.Entry.Code), " ")
+		if code == "" {
+			continue
+		}
+		runes := []rune(word)
+		syllables := strings.Fields(code)
+		if len(runes) == 1 {
+			readings := e.readings
+			if se.Entry.Weight.IsNumeric() && se.Entry.Weight.Value == 0 {
+				readings = rare
+			}
+			if !slices.Contains(readings[runes[0]], code) {
+				readings[runes[0]] = append(readings[runes[0]], code)
+			}
+			continue
+		}
+		if len(syllables) != len(runes) {
+			continue // Not one syllable per character, so of no use
+		}
+		if p, ok := e.phrases[word]; ok && !heavier(se.Entry.Weight, p.weight) {
+			continue
+		}
+		e.phrases[word] = pinyinPhrase{syllables: syllables, weight: se.Entry.Weight}
+		e.maxPhrase = max(e.maxPhrase, len(runes))
+	}
+	for r, codes := range rare {
+		if len(e.readings[r]) == 0 {
+			e.readings[r] = codes
+		}
+	}
+	return e
+}
+
+// GenerateCode generates the pinyin of a word. The word is split greedily
+// into the longest phrases the dictionary knows; characters outside any
+// phrase get their own reading. If such a character has several readings
+// it returns an *AmbiguousCodeError listing every possible code.
+func (e *PinyinEncoder) GenerateCode(word string) (string, error) {
+	runes := []rune(word)
+	if len(runes) == 0 {
+		return "", nil
+	}
+
+	// The possible syllables of each segment of the word.
+	var segments [][]string
+	var missing []rune
+	for i := 0; i < len(runes); {
+		if p, n := e.longestPhrase(runes[i:]); n > 0 {
+			segments = append(segments, []string{strings.Join(p.syllables, " ")})
+			i += n
+			continue
+		}
+		readings := e.readings[runes[i]]
+		if len(readings) == 0 && !slices.Contains(missing, runes[i]) {
+			missing = append(missing, runes[i])
+		}
+		segments = append(segments, readings)
+		i++
+	}
+	if err := newMissingCharError(missing); err != nil {
+		return "", err
+	}
+
+	candidates := []string{""}
+	for _, options := range segments {
+		var next []string
+		for _, prefix := range candidates {
+			for _, option := range options {
+				next = append(next, strings.TrimPrefix(prefix+" "+option, " "))
+			}
+		}
+		candidates = next
+	}
+
+	if len(candidates) > 1 {
+		sort.Strings(candidates)
+		return "", &AmbiguousCodeError{Word: word, Candidates: candidates}
+	}
+	return candidates[0], nil
+}
+
+// longestPhrase returns the longest phrase of at least two characters
+// that runes starts with, and its length; 0 if there is none.
+func (e *PinyinEncoder) longestPhrase(runes []rune) (pinyinPhrase, int) {
+	for n := min(e.maxPhrase, len(runes)); n >= 2; n-- {
+		if p, ok := e.phrases[string(runes[:n])]; ok {
+			return p, n
+		}
+	}
+	return pinyinPhrase{}, 0
+}
+
+// heavier reports whether weight a ranks above weight b. Absent and
+// invalid weights rank lowest; weights of different kinds do not compare,
+// so neither is heavier.
+func heavier(a, b Weight) bool {
+	if !b.IsNumeric() {
+		return a.IsNumeric()
+	}
+	return a.Kind == b.Kind && a.Value > b.Value
+}
+
+func init() {
+	RegisterScheme("pinyin", func(opts EncoderOptions) (Encoder, error) {
+		return NewPinyinEncoderFromView(opts.Main), nil
+	})
+}
@@ -0,0 +1,73 @@
+package dict
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const pinyinSampleDict = "testdata/luna_pinyin_sample.dict.yaml"
+
+func newSamplePinyinEncoder(t *testing.T) *PinyinEncoder {
+	t.Helper()
+	view, err := LoadMerged("", pinyinSampleDict)
+	if err != nil {
+		t.Fatalf("LoadMerged failed: %v", err)
+	}
+	return NewPinyinEncoderFromView(view)
+}
+
+func TestPinyinEncoder_GenerateCode(t *testing.T) {
+	encoder := newSamplePinyinEncoder(t)
+
+	testCases := []struct {
+		name     string
+		word     string
+		expected string
+	}{
+		{"one reading per char", "测试", "ce shi"},
+		{"duplicate readings", "中", "zhong"},
+		{"known phrase", "银行", "yin hang"},
+		{"phrases cover polyphones", "银行行长", "yin hang hang zhang"},
+		{"phrase and single chars", "重庆测试", "chong qing ce shi"},
+		{"rare reading ignored", "中国的", "zhong guo de"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			code, err := encoder.GenerateCode(tc.word)
+			if err != nil {
+				t.Fatalf("GenerateCode(%s) failed: %v", tc.word, err)
+			}
+			if code != tc.expected {
+				t.Errorf("GenerateCode(%s) = %s, want %s", tc.word, code, tc.expected)
+			}
+		})
+	}
+}
+
+func TestPinyinEncoder_MissingChars(t *testing.T) {
+	encoder := newSamplePinyinEncoder(t)
+
+	_, err := encoder.GenerateCode("囗测龘囗")
+	var missing *MissingCharError
+	if !errors.As(err, &missing) || string(missing.Chars) != "囗龘" {
+		t.Errorf("GenerateCode(囗测龘囗) error = %v, want a *MissingCharError for 囗 and 龘", err)
+	}
+}
+
+func TestPinyinEncoder_Ambiguous(t *testing.T) {
+	encoder := newSamplePinyinEncoder(t)
+
+	_, err := encoder.GenerateCode("重要")
+	var ambiguous *AmbiguousCodeError
+	if !errors.As(err, &ambiguous) {
+		t.Fatalf("GenerateCode(重要) error = %v, want an *AmbiguousCodeError", err)
+	}
+	if expected := []string{"chong yao", "zhong yao"}; !reflect.DeepEqual(ambiguous.Candidates, expected) {
+		t.Errorf("Candidates = %v, want %v", ambiguous.Candidates, expected)
+	}
+
+	if _, err := encoder.GenerateCode("测验"); err == nil || errors.As(err, &ambiguous) {
+		t.Errorf("GenerateCode(测验) should report the missing character, got: %v", err)
+	}
+}
@@ -0,0 +1,283 @@
+package dict
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// EncodingRule is one of the phrase rules a Rime table schema declares
+// under `encoder: rules:`. Formula applies to words of MinLength to
+// MaxLength characters; a MaxLength of 0 means no upper limit.
+//
+// A formula is a sequence of letter pairs. The uppercase letter picks a
+// character of the word and the lowercase letter picks a letter of that
+// character's full code. A–T count from the start (A is the first) and
+// U–Z from the end (Z is the last), and likewise a–t and u–z. For example
+// "AaBaCaZa" takes the first letter of the first three and the last
+// characters.
+type EncodingRule struct {
+	MinLength int
+	MaxLength int
+	Formula   string
+}
+
+// Wubi86Rules are the phrase rules of the wubi86 schemas.
+var Wubi86Rules = []EncodingRule{
+	{MinLength: 2, MaxLength: 2, Formula: "AaAbBaBb"},
+	{MinLength: 3, MaxLength: 3, Formula: "AaBaCaCb"},
+	{MinLength: 4, Formula: "AaBaCaZa"},
+}
+
+// codeCoord is one letter pair of a formula. Negative indexes count from
+// the end.
+type codeCoord struct {
+	char, code int
+}
+
+// Matches reports whether the rule applies to words of length n.
+func (r EncodingRule) Matches(n int) bool {
+	return n >= r.MinLength && (r.MaxLength == 0 || n <= r.MaxLength)
+}
+
+// coords parses the rule's formula.
+func (r EncodingRule) coords() ([]codeCoord, error) {
+	f := r.Formula
+	if f == "" || len(f)%2 != 0 {
+		return nil, fmt.Errorf("invalid formula '%s': must be pairs of an uppercase and a lowercase letter", f)
+	}
+	var coords []codeCoord
+	for i := 0; i < len(f); i += 2 {
+		c, l := f[i], f[i+1]
+		if c < 'A' || c > 'Z' || l < 'a' || l > 'z' {
+			return nil, fmt.Errorf("invalid formula '%s': must be pairs of an uppercase and a lowercase letter", f)
+		}
+		coord := codeCoord{char: int(c - 'A'), code: int(l - 'a')}
+		if c >= 'U' {
+			coord.char = int(c) - 'Z' - 1
+		}
+		if l >= 'u' {
+			coord.code = int(l) - 'z' - 1
+		}
+		coords = append(coords, coord)
+	}
+	return coords, nil
+}
+
+// String formats the rule the way ParseRuleSpec reads it.
+func (r EncodingRule) String() string {
+	switch {
+	case r.MinLength == r.MaxLength:
+		return fmt.Sprintf("%d:%s", r.MinLength, r.Formula)
+	case r.MaxLength == 0:
+		return fmt.Sprintf("%d-:%s", r.MinLength, r.Formula)
+	}
+	return fmt.Sprintf("%d-%d:%s", r.MinLength, r.MaxLength, r.Formula)
+}
+
+// ParseRuleSpec parses rules written as comma separated "length:formula"
+// items, where length is "N", "N-M" or "N-" for N or more, e.g.
+// "2:AaAbBaBb,3:AaBaCaCb,4-:AaBaCaZa".
+func ParseRuleSpec(spec string) ([]EncodingRule, error) {
+	var rules []EncodingRule
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		length, formula, ok := strings.Cut(item, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid rule '%s': must be length:formula", item)
+		}
+		rule := EncodingRule{Formula: strings.TrimSpace(formula)}
+		from, to, isRange := strings.Cut(strings.TrimSpace(length), "-")
+		var err error
+		if rule.MinLength, err = strconv.Atoi(from); err != nil || rule.MinLength < 1 {
+			return nil, fmt.Errorf("invalid rule '%s': bad word length '%s'", item, length)
+		}
+		rule.MaxLength = rule.MinLength
+		if isRange {
+			rule.MaxLength = 0
+			if to != "" {
+				if rule.MaxLength, err = strconv.Atoi(to); err != nil || rule.MaxLength < rule.MinLength {
+					return nil, fmt.Errorf("invalid rule '%s': bad word length '%s'", item, length)
+				}
+			}
+		}
+		if _, err := rule.coords(); err != nil {
+			return nil, fmt.Errorf("invalid rule '%s': %w", item, err)
+		}
+		rules = append(rules, rule)
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("no rules given")
+	}
+	return rules, nil
+}
+
+// LoadSchemaRules reads the `encoder: rules:` of a Rime schema file. It
+// returns no rules and no error if the schema declares none.
+func LoadSchemaRules(path string) ([]EncodingRule, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema '%s': %w", path, err)
+	}
+	rules, err := parseSchemaRules(strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid encoder rules in '%s': %w", path, err)
+	}
+	return rules, nil
+}
+
+// parseSchemaRules finds the rules list under the top-level encoder key.
+// Each rule is either a flow mapping,
+//
+//   - {length_equal: 2, formula: "AaAbBaBb"}
+//
+// or a block mapping:
+//
+//   - length_in_range: [4, 10]
+//     formula: "AaBaCaZa"
+func parseSchemaRules(lines []string) ([]EncodingRule, error) {
+	// Find `encoder:` and then `rules:` inside its block.
+	i := 0
+	for ; i < len(lines) && !strings.HasPrefix(lines[i], "encoder:"); i++ {
+	}
+	rulesIndent := -1
+	for i++; i < len(lines); i++ {
+		line := lines[i]
+		if isBlankOrComment(line) {
+			continue
+		}
+		indent := indentOf(line)
+		if indent == 0 {
+			return nil, nil // left the encoder block
+		}
+		if strings.HasPrefix(strings.TrimSpace(line), "rules:") {
+			rulesIndent = indent
+			i++
+			break
+		}
+	}
+	if rulesIndent < 0 {
+		return nil, nil
+	}
+
+	// Group the lines of each '- ' item.
+	var items [][]string
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if isBlankOrComment(line) {
+			continue
+		}
+		trimmed := strings.TrimSpace(line)
+		indent := indentOf(line)
+		if indent < rulesIndent || indent == rulesIndent && !strings.HasPrefix(trimmed, "-") {
+			break
+		}
+		if strings.HasPrefix(trimmed, "-") && (len(items) == 0 || indent <= indentOf(items[len(items)-1][0])) {
+			items = append(items, []string{line})
+			continue
+		}
+		if len(items) == 0 {
+			break
+		}
+		items[len(items)-1] = append(items[len(items)-1], line)
+	}
+
+	var rules []EncodingRule
+	for _, item := range items {
+		fields := make(map[string]string)
+		first := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(item[0]), "-"))
+		if strings.HasPrefix(first, "{") {
+			body := strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(strings.Join(append([]string{first}, item[1:]...), " ")), "{"), "}")
+			for _, pair := range splitFlowMapping(body) {
+				addSchemaField(fields, pair)
+			}
+		} else {
+			addSchemaField(fields, first)
+			for _, line := range item[1:] {
+				addSchemaField(fields, strings.TrimSpace(line))
+			}
+		}
+
+		rule, err := schemaRule(fields)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func schemaRule(fields map[string]string) (EncodingRule, error) {
+	rule := EncodingRule{Formula: fields["formula"]}
+	if v, ok := fields["length_equal"]; ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return rule, fmt.Errorf("bad length_equal '%s'", v)
+		}
+		rule.MinLength, rule.MaxLength = n, n
+	} else if v, ok := fields["length_in_range"]; ok {
+		bounds := splitFlowSequence(v)
+		if len(bounds) != 2 {
+			return rule, fmt.Errorf("bad length_in_range '%s'", v)
+		}
+		var err1, err2 error
+		rule.MinLength, err1 = strconv.Atoi(bounds[0])
+		rule.MaxLength, err2 = strconv.Atoi(bounds[1])
+		if err1 != nil || err2 != nil || rule.MaxLength < rule.MinLength {
+			return rule, fmt.Errorf("bad length_in_range '%s'", v)
+		}
+	} else {
+		return rule, fmt.Errorf("rule '%s' has neither length_equal nor length_in_range", rule.Formula)
+	}
+	if _, err := rule.coords(); err != nil {
+		return rule, err
+	}
+	return rule, nil
+}
+
+func addSchemaField(fields map[string]string, pair string) {
+	key, value, ok := strings.Cut(pair, ":")
+	if !ok {
+		return
+	}
+	fields[strings.TrimSpace(key)] = unquoteScalar(value)
+}
+
+// splitFlowMapping splits the body of a YAML flow mapping on the commas
+// that are not inside brackets or quotes.
+func splitFlowMapping(body string) []string {
+	var parts []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(body); i++ {
+		switch c := body[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		case c == ',' && depth == 0:
+			parts = append(parts, strings.TrimSpace(body[start:i]))
+			start = i + 1
+		}
+	}
+	return append(parts, strings.TrimSpace(body[start:]))
+}
+
+func isBlankOrComment(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed == "" || strings.HasPrefix(trimmed, "#")
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
@@ -0,0 +1,100 @@
+package dict
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseRuleSpec(t *testing.T) {
+	rules, err := ParseRuleSpec("2:AaAbBaBb, 3:AaBaCaCb,4-:AaBaCaZa,5-9:AaBaCaZa")
+	if err != nil {
+		t.Fatalf("ParseRuleSpec failed: %v", err)
+	}
+	expected := []EncodingRule{
+		{MinLength: 2, MaxLength: 2, Formula: "AaAbBaBb"},
+		{MinLength: 3, MaxLength: 3, Formula: "AaBaCaCb"},
+		{MinLength: 4, Formula: "AaBaCaZa"},
+		{MinLength: 5, MaxLength: 9, Formula: "AaBaCaZa"},
+	}
+	if !reflect.DeepEqual(rules, expected) {
+		t.Errorf("ParseRuleSpec = %v, want %v", rules, expected)
+	}
+
+	var specs []string
+	for _, rule := range rules {
+		specs = append(specs, rule.String())
+	}
+	if got := strings.Join(specs, ","); got != "2:AaAbBaBb,3:AaBaCaCb,4-:AaBaCaZa,5-9:AaBaCaZa" {
+		t.Errorf("String() round trip = %s", got)
+	}
+
+	for _, spec := range []string{"", "AaAb", "0:AaAb", "3-2:AaAb", "2:Aab", "2:aAbB"} {
+		if _, err := ParseRuleSpec(spec); err == nil {
+			t.Errorf("ParseRuleSpec(%q) should fail", spec)
+		}
+	}
+}
+
+func TestEncodingRule_Coords(t *testing.T) {
+	coords, err := EncodingRule{Formula: "AaBtUuZz"}.coords()
+	if err != nil {
+		t.Fatalf("coords failed: %v", err)
+	}
+	expected := []codeCoord{{0, 0}, {1, 19}, {-6, -6}, {-1, -1}}
+	if !reflect.DeepEqual(coords, expected) {
+		t.Errorf("coords = %v, want %v", coords, expected)
+	}
+}
+
+func TestLoadSchemaRules(t *testing.T) {
+	schema := `# Rime schema
+schema:
+  schema_id: cangjie5
+encoder:
+  exclude_patterns:
+    - '^z.*$'
+  rules:
+    - length_equal: 2
+      formula: "AaAzBaBbBz"
+    - {length_equal: 3, formula: "AaAzBaBzCz"}
+    # Longer words
+    - length_in_range: [4, 10]
+      formula: "AaBzCaYzZz"
+  tail_anchor: "'"
+translator:
+  dictionary: cangjie5
+`
+	path := filepath.Join(t.TempDir(), "cangjie5.schema.yaml")
+	os.WriteFile(path, []byte(schema), 0o644)
+
+	rules, err := LoadSchemaRules(path)
+	if err != nil {
+		t.Fatalf("LoadSchemaRules failed: %v", err)
+	}
+	expected := []EncodingRule{
+		{MinLength: 2, MaxLength: 2, Formula: "AaAzBaBbBz"},
+		{MinLength: 3, MaxLength: 3, Formula: "AaAzBaBzCz"},
+		{MinLength: 4, MaxLength: 10, Formula: "AaBzCaYzZz"},
+	}
+	if !reflect.DeepEqual(rules, expected) {
+		t.Errorf("LoadSchemaRules = %v, want %v", rules, expected)
+	}
+}
+
+func TestLoadSchemaRules_NoRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "luna_pinyin.schema.yaml")
+	os.WriteFile(path, []byte("schema:\n  schema_id: luna_pinyin\ntranslator:\n  dictionary: luna_pinyin\n"), 0o644)
+
+	rules, err := LoadSchemaRules(path)
+	if err != nil || rules != nil {
+		t.Errorf("LoadSchemaRules = %v, %v; want no rules and no error", rules, err)
+	}
+
+	os.WriteFile(path, []byte("encoder:\n  rules:\n    - {formula: \"AaAb\"}\n"), 0o644)
+	if _, err := LoadSchemaRules(path); err == nil {
+		t.Error("LoadSchemaRules should reject a rule without a length")
+	}
+}
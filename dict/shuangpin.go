@@ -0,0 +1,173 @@
+package dict
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ShuangpinLayout maps full pinyin syllables to the two keys of a
+// double pinyin (双拼) layout.
+type ShuangpinLayout struct {
+	Name   string
+	finals map[string]string // Key of every final longer than one letter, and of v
+	// zeroInitial is the key typed first for syllables without an
+	// initial. If empty, such syllables are typed Xiaohe style: a one
+	// letter final twice, a two letter final as is, and a longer one as
+	// its first letter and its key.
+	zeroInitial string
+}
+
+// Built-in double pinyin layouts. All of them type zh, ch and sh as v, i
+// and u.
+var (
+	XiaoheLayout = &ShuangpinLayout{
+		Name: "xiaohe",
+		finals: map[string]string{
+			"iu": "q", "ei": "w", "uan": "r", "van": "r", "ue": "t", "ve": "t",
+			"un": "y", "vn": "y", "uo": "o", "ie": "p", "ong": "s", "iong": "s",
+			"ai": "d", "en": "f", "eng": "g", "ang": "h", "an": "j", "uai": "k",
+			"ing": "k", "iang": "l", "uang": "l", "ou": "z", "ia": "x", "ua": "x",
+			"ao": "c", "ui": "v", "v": "v", "in": "b", "iao": "n", "ian": "m",
+		},
+	}
+	ZiranmaLayout = &ShuangpinLayout{
+		Name: "ziranma",
+		finals: map[string]string{
+			"iu": "q", "ia": "w", "ua": "w", "uan": "r", "van": "r", "ue": "t",
+			"ve": "t", "ing": "y", "uai": "y", "uo": "o", "un": "p", "vn": "p",
+			"ong": "s", "iong": "s", "iang": "d", "uang": "d", "en": "f", "eng": "g",
+			"ang": "h", "an": "j", "ao": "k", "ai": "l", "ei": "z", "ie": "x",
+			"iao": "c", "ui": "v", "v": "v", "ou": "b", "in": "n", "ian": "m",
+		},
+	}
+	MSPYLayout = &ShuangpinLayout{
+		Name: "mspy",
+		finals: map[string]string{
+			"iu": "q", "ia": "w", "ua": "w", "uan": "r", "van": "r", "er": "r",
+			"ue": "t", "uai": "y", "v": "y", "uo": "o", "un": "p", "vn": "p",
+			"ong": "s", "iong": "s", "iang": "d", "uang": "d", "en": "f", "eng": "g",
+			"ang": "h", "an": "j", "ao": "k", "ai": "l", "ing": ";", "ei": "z",
+			"ie": "x", "iao": "c", "ui": "v", "ve": "v", "ou": "b", "in": "n",
+			"ian": "m",
+		},
+		zeroInitial: "o",
+	}
+	SogouLayout = &ShuangpinLayout{
+		Name: "sogou",
+		finals: map[string]string{
+			"iu": "q", "ia": "w", "ua": "w", "uan": "r", "van": "r", "er": "r",
+			"ue": "t", "ve": "t", "uai": "y", "v": "y", "uo": "o", "un": "p",
+			"vn": "p", "ong": "s", "iong": "s", "iang": "d", "uang": "d", "en": "f",
+			"eng": "g", "ang": "h", "an": "j", "ao": "k", "ai": "l", "ing": ";",
+			"ei": "z", "ie": "x", "iao": "c", "ui": "v", "ou": "b", "in": "n",
+			"ian": "m",
+		},
+		zeroInitial: "o",
+	}
+)
+
+// ShuangpinLayouts lists the built-in layouts.
+var ShuangpinLayouts = []*ShuangpinLayout{XiaoheLayout, ZiranmaLayout, MSPYLayout, SogouLayout}
+
+var shuangpinInitials = map[string]string{"zh": "v", "ch": "i", "sh": "u"}
+
+// Convert converts full pinyin, syllables separated by spaces, to the
+// layout's keys, keeping the syllables apart. ü is written v, as in Rime's
+// pinyin dictionaries.
+func (l *ShuangpinLayout) Convert(pinyin string) (string, error) {
+	syllables := strings.Fields(pinyin)
+	for i, syllable := range syllables {
+		keys, err := l.convertSyllable(syllable)
+		if err != nil {
+			return "", err
+		}
+		syllables[i] = keys
+	}
+	return strings.Join(syllables, " "), nil
+}
+
+func (l *ShuangpinLayout) convertSyllable(syllable string) (string, error) {
+	initial, final := splitSyllable(strings.ToLower(syllable))
+	if final == "" {
+		return "", fmt.Errorf("'%s' is not a pinyin syllable", syllable)
+	}
+	key, ok := l.finals[final]
+	if !ok && len(final) == 1 && strings.Contains("aoeiu", final) {
+		key, ok = final, true
+	}
+
+	if initial == "" {
+		switch {
+		case l.zeroInitial != "" && ok:
+			return l.zeroInitial + key, nil
+		case len(final) == 1 && ok:
+			return final + final, nil
+		case len(final) == 2 && final != "v":
+			return final, nil
+		case len(final) > 2 && ok:
+			return final[:1] + key, nil
+		}
+		return "", fmt.Errorf("'%s' is not a pinyin syllable", syllable)
+	}
+	if !ok {
+		return "", fmt.Errorf("'%s' is not a pinyin syllable", syllable)
+	}
+	if k, ok := shuangpinInitials[initial]; ok {
+		initial = k
+	}
+	return initial + key, nil
+}
+
+// splitSyllable splits a pinyin syllable into its initial and final.
+// Syllables such as "an" have no initial.
+func splitSyllable(syllable string) (initial, final string) {
+	if len(syllable) >= 2 {
+		if _, ok := shuangpinInitials[syllable[:2]]; ok {
+			return syllable[:2], syllable[2:]
+		}
+	}
+	if syllable != "" && strings.Contains("bpmfdtnlgkhjqxrzcsyw", syllable[:1]) {
+		return syllable[:1], syllable[1:]
+	}
+	return "", syllable
+}
+
+// ShuangpinEncoder generates double pinyin codes by converting the codes
+// of a full pinyin encoder.
+type ShuangpinEncoder struct {
+	Pinyin Encoder
+	Layout *ShuangpinLayout
+}
+
+// GenerateCode generates the double pinyin of a word. An ambiguous full
+// pinyin code stays ambiguous, with every candidate converted.
+func (e *ShuangpinEncoder) GenerateCode(word string) (string, error) {
+	code, err := e.Pinyin.GenerateCode(word)
+	var ambiguous *AmbiguousCodeError
+	if errors.As(err, &ambiguous) {
+		converted := &AmbiguousCodeError{Word: ambiguous.Word}
+		for _, candidate := range ambiguous.Candidates {
+			c, err := e.Layout.Convert(candidate)
+			if err != nil {
+				return "", err
+			}
+			converted.Candidates = append(converted.Candidates, c)
+		}
+		sort.Strings(converted.Candidates)
+		return "", converted
+	}
+	if err != nil {
+		return "", err
+	}
+	return e.Layout.Convert(code)
+}
+
+func init() {
+	for _, layout := range ShuangpinLayouts {
+		RegisterScheme("shuangpin-"+layout.Name, func(opts EncoderOptions) (Encoder, error) {
+			return &ShuangpinEncoder{Pinyin: NewPinyinEncoderFromView(opts.Main), Layout: layout}, nil
+		})
+	}
+}
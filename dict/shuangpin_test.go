@@ -0,0 +1,61 @@
+package dict
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestShuangpinLayout_Convert(t *testing.T) {
+	testCases := []struct {
+		layout   *ShuangpinLayout
+		pinyin   string
+		expected string
+	}{
+		{XiaoheLayout, "ce shi", "ce ui"},
+		{XiaoheLayout, "zhong guo", "vs go"},
+		{XiaoheLayout, "shuang chuang", "ul il"},
+		{XiaoheLayout, "xing lve nv jue", "xk lt nv jt"},
+		{XiaoheLayout, "a e o ai er ang eng", "aa ee oo ai er ah eg"},
+		{ZiranmaLayout, "xiang ying zhei ou", "xd yy vz ou"},
+		{ZiranmaLayout, "lve ang", "lt ah"},
+		{MSPYLayout, "xing lv lve jue", "x; ly lv jt"},
+		{MSPYLayout, "a o ai er ou", "oa oo ol or ob"},
+		{SogouLayout, "xing lv lve", "x; ly lt"},
+		{SogouLayout, "ang eng", "oh og"},
+	}
+	for _, tc := range testCases {
+		got, err := tc.layout.Convert(tc.pinyin)
+		if err != nil {
+			t.Errorf("%s: Convert(%s) failed: %v", tc.layout.Name, tc.pinyin, err)
+			continue
+		}
+		if got != tc.expected {
+			t.Errorf("%s: Convert(%s) = %s, want %s", tc.layout.Name, tc.pinyin, got, tc.expected)
+		}
+	}
+
+	for _, bad := range []string{"xyz", "zh", "hm"} {
+		if _, err := XiaoheLayout.Convert(bad); err == nil {
+			t.Errorf("Convert(%s) should fail", bad)
+		}
+	}
+}
+
+func TestShuangpinEncoder(t *testing.T) {
+	encoder := &ShuangpinEncoder{Pinyin: newSamplePinyinEncoder(t), Layout: XiaoheLayout}
+
+	code, err := encoder.GenerateCode("银行行长")
+	if err != nil || code != "yb hh hh vh" {
+		t.Errorf("GenerateCode(银行行长) = %s, %v; want yb hh hh vh", code, err)
+	}
+
+	_, err = encoder.GenerateCode("重要")
+	var ambiguous *AmbiguousCodeError
+	if !errors.As(err, &ambiguous) {
+		t.Fatalf("GenerateCode(重要) error = %v, want an *AmbiguousCodeError", err)
+	}
+	if expected := []string{"is yc", "vs yc"}; !reflect.DeepEqual(ambiguous.Candidates, expected) {
+		t.Errorf("Candidates = %v, want %v", ambiguous.Candidates, expected)
+	}
+}
@@ -0,0 +1,255 @@
+package dict
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// TableEncoder generates codes for the words of a table-based input
+// method, such as Wubi, Zhengma or Cangjie, from the codes of their
+// characters and a set of phrase rules.
+type TableEncoder struct {
+	Rules   []EncodingRule
+	charMap map[rune][]string // Every code of each character, in file order
+}
+
+// NewTableEncoderFromReader creates an encoder from the single characters
+// of a main dictionary read from r.
+func NewTableEncoderFromReader(r io.Reader, rules []EncodingRule) (*TableEncoder, error) {
+	e := &TableEncoder{Rules: rules, charMap: make(map[rune][]string)}
+
+	reader := newLineReader(r)
+	for {
+		line, _, err := reader.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading main dictionary: %w", err)
+		}
+		if strings.HasPrefix(line, "#") || strings.TrimSpace(line) == "" {
+			continue
+		}
+		parts := strings.Split(line, "\t")
+		if len(parts) >= 2 {
+			char := []rune(parts[0])
+			if len(char) == 1 {
+				// We only care about single characters for building words
+				e.addCode(char[0], parts[1])
+			}
+		}
+	}
+
+	return e, nil
+}
+
+// NewTableEncoderFromView creates an encoder from the single characters of
+// a merged dictionary view.
+func NewTableEncoderFromView(v *MergedView, rules []EncodingRule) *TableEncoder {
+	e := &TableEncoder{Rules: rules, charMap: make(map[rune][]string)}
+	for _, se := range v.Entries {
+		char := []rune(se.Entry.Word)
+		if len(char) == 1 && se.Entry.Code != "" {
+			e.addCode(char[0], se.Entry.Code)
+		}
+	}
+	return e
+}
+
+// AmbiguousCodeError is returned when a character has several full codes
+// that lead to different codes for the word.
+type AmbiguousCodeError struct {
+	Word       string
+	Candidates []string // Every code the word could get, sorted
+}
+
+func (e *AmbiguousCodeError) Error() string {
+	return fmt.Sprintf("'%s' has several possible codes: %s", e.Word, strings.Join(e.Candidates, ", "))
+}
+
+// MissingCharError is returned when a word contains characters the main
+// dictionary has no code for.
+type MissingCharError struct {
+	Char  rune   // The first missing character
+	Chars []rune // Every missing character, in word order
+}
+
+// newMissingCharError returns the error for the missing chars, or nil if
+// there are none.
+func newMissingCharError(chars []rune) error {
+	if len(chars) == 0 {
+		return nil
+	}
+	return &MissingCharError{Char: chars[0], Chars: chars}
+}
+
+func (e *MissingCharError) Error() string {
+	if len(e.Chars) <= 1 {
+		return fmt.Sprintf("character '%c' not found in main dictionary", e.Char)
+	}
+	quoted := make([]string, len(e.Chars))
+	for i, r := range e.Chars {
+		quoted[i] = fmt.Sprintf("'%c'", r)
+	}
+	return fmt.Sprintf("characters %s not found in main dictionary", strings.Join(quoted, ", "))
+}
+
+// addCode records a code for a character, ignoring duplicates.
+func (e *TableEncoder) addCode(r rune, code string) {
+	if slices.Contains(e.charMap[r], code) {
+		return
+	}
+	e.charMap[r] = append(e.charMap[r], code)
+}
+
+// fullCodes returns the full codes of a character: its longest codes.
+// Shorter codes are simple codes (简码) and are not used to form phrases.
+func (e *TableEncoder) fullCodes(r rune) []string {
+	var full []string
+	for _, code := range e.charMap[r] {
+		switch {
+		case len(full) == 0 || len(code) > len(full[0]):
+			full = []string{code}
+		case len(code) == len(full[0]):
+			full = append(full, code)
+		}
+	}
+	return full
+}
+
+// GenerateCode generates a code for a given word. A single character gets
+// its full code; a longer word gets the code built by the first rule that
+// matches its length, from the full codes of its characters. If a
+// character has several full codes that lead to different results, it
+// returns an *AmbiguousCodeError listing them instead of guessing.
+func (e *TableEncoder) GenerateCode(word string) (string, error) {
+	runes := []rune(word)
+	if len(runes) == 0 {
+		return "", nil
+	}
+
+	_, coords, err := e.formula(len(runes))
+	if err != nil {
+		return "", err
+	}
+
+	// The characters the formula uses, by index into runes.
+	var used []int
+	if coords == nil {
+		used = []int{0}
+	}
+	for _, c := range coords {
+		if i, ok := resolveIndex(c.char, len(runes)); ok && !slices.Contains(used, i) {
+			used = append(used, i)
+		}
+	}
+	var missing []rune
+	for i := range runes {
+		if slices.Contains(used, i) && len(e.fullCodes(runes[i])) == 0 && !slices.Contains(missing, runes[i]) {
+			missing = append(missing, runes[i])
+		}
+	}
+	if err := newMissingCharError(missing); err != nil {
+		return "", err
+	}
+
+	// Encode the word with every combination of the used characters'
+	// full codes.
+	assignment := make(map[int]string)
+	var candidates []string
+	var assign func(n int)
+	assign = func(n int) {
+		if n == len(used) {
+			code, _ := applyCoords(coords, runes, assignment)
+			if !slices.Contains(candidates, code) {
+				candidates = append(candidates, code)
+			}
+			return
+		}
+		for _, code := range e.fullCodes(runes[used[n]]) {
+			assignment[used[n]] = code
+			assign(n + 1)
+		}
+	}
+	assign(0)
+
+	if len(candidates) > 1 {
+		sort.Strings(candidates)
+		return "", &AmbiguousCodeError{Word: word, Candidates: candidates}
+	}
+	return candidates[0], nil
+}
+
+// formula returns the rule for words of n characters and its parsed
+// formula. A single character needs no rule, so both are nil for it when
+// no rule matches.
+func (e *TableEncoder) formula(n int) (*EncodingRule, []codeCoord, error) {
+	rule, ok := e.ruleFor(n)
+	if !ok {
+		if n > 1 {
+			return nil, nil, fmt.Errorf("no encoding rule for words of %d characters", n)
+		}
+		return nil, nil, nil
+	}
+	coords, err := rule.coords()
+	if err != nil {
+		return nil, nil, err
+	}
+	return &rule, coords, nil
+}
+
+// ruleFor returns the first rule for words of n characters.
+func (e *TableEncoder) ruleFor(n int) (EncodingRule, bool) {
+	for _, rule := range e.Rules {
+		if rule.Matches(n) {
+			return rule, true
+		}
+	}
+	return EncodingRule{}, false
+}
+
+// applyCoords builds a code from the characters' chosen full codes and
+// reports the letter each pair took. With no coords the word is a single
+// character and gets its full code. Pairs pointing past the end of a word
+// or code are skipped, as is a pair that would reuse or go back on the
+// letters of the character taken just before it.
+func applyCoords(coords []codeCoord, runes []rune, codes map[int]string) (string, []LetterPick) {
+	if coords == nil {
+		return codes[0], nil
+	}
+	var b strings.Builder
+	var picks []LetterPick
+	lastChar, lastCode := -1, -1
+	for _, c := range coords {
+		pick := LetterPick{CharIndex: -1, CodeIndex: -1, Skipped: true}
+		i, ok := resolveIndex(c.char, len(runes))
+		if !ok {
+			picks = append(picks, pick)
+			continue
+		}
+		code := codes[i]
+		pick.CharIndex, pick.Char, pick.FullCode = i, runes[i], code
+		k, ok := resolveIndex(c.code, len(code))
+		if !ok || i == lastChar && k <= lastCode {
+			picks = append(picks, pick)
+			continue
+		}
+		b.WriteByte(code[k])
+		pick.CodeIndex, pick.Letter, pick.Skipped = k, code[k:k+1], false
+		picks = append(picks, pick)
+		lastChar, lastCode = i, k
+	}
+	return b.String(), picks
+}
+
+// resolveIndex turns a possibly negative index into an index into a
+// sequence of length n.
+func resolveIndex(index, n int) (int, bool) {
+	if index < 0 {
+		index += n
+	}
+	return index, index >= 0 && index < n
+}
@@ -0,0 +1,63 @@
+package dict
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// cangjie5Rules are the phrase rules of the cangjie5 schema, which pick
+// letters from the end of the codes as well as from the start.
+var cangjie5Rules = []EncodingRule{
+	{MinLength: 2, MaxLength: 2, Formula: "AaAzBaBbBz"},
+	{MinLength: 3, MaxLength: 3, Formula: "AaAzBaBzCz"},
+	{MinLength: 4, MaxLength: 10, Formula: "AaBzCaYzZz"},
+}
+
+func TestTableEncoder_Rules(t *testing.T) {
+	main := "日\ta\n月\tb\n明\tab\n天\tmk\n中\tl\n國\twirm\n人\to\n"
+	encoder, err := NewTableEncoderFromReader(strings.NewReader(main), cangjie5Rules)
+	if err != nil {
+		t.Fatalf("NewTableEncoderFromReader failed: %v", err)
+	}
+
+	testCases := []struct {
+		word     string
+		expected string
+	}{
+		{"明", "ab"},
+		// 日 has a one letter code, so Az would take the same letter as Aa.
+		{"日月", "ab"},
+		{"中國", "lwim"},
+		{"天明", "mkab"},
+		{"中國人", "lwmo"},
+		// Y and C both pick 人, whose one letter is already taken.
+		{"中國人明", "lmob"},
+	}
+	for _, tc := range testCases {
+		code, err := encoder.GenerateCode(tc.word)
+		if err != nil {
+			t.Errorf("GenerateCode(%s) failed: %v", tc.word, err)
+			continue
+		}
+		if code != tc.expected {
+			t.Errorf("GenerateCode(%s) = %s, want %s", tc.word, code, tc.expected)
+		}
+	}
+
+	_, err = encoder.GenerateCode("日光")
+	var missing *MissingCharError
+	if !errors.As(err, &missing) || missing.Char != '光' {
+		t.Errorf("GenerateCode(日光) error = %v, want a *MissingCharError for 光", err)
+	}
+
+	// Every missing character the rule uses is reported, in word order.
+	_, err = encoder.GenerateCode("光日火")
+	if !errors.As(err, &missing) || string(missing.Chars) != "光火" {
+		t.Errorf("GenerateCode(光日火) error = %v, want a *MissingCharError for 光 and 火", err)
+	}
+
+	if _, err := encoder.GenerateCode("日月日月日月日月日月日"); err == nil {
+		t.Error("GenerateCode should fail when no rule covers the word length")
+	}
+}
@@ -0,0 +1,207 @@
+package dict
+
+import (
+	"errors"
+	"slices"
+	"sort"
+)
+
+// LengthReport is the result of VerifyEncoder for the phrases of one
+// length.
+type LengthReport struct {
+	Length     int
+	Phrases    int
+	Mismatches int // Phrases the encoder gives another code than the dictionary
+	Failures   int // Phrases the encoder cannot encode at all
+	Examples   []Mismatch
+}
+
+// Mismatch is a phrase whose generated code differs from the dictionary.
+type Mismatch struct {
+	Word     string
+	Expected []string // The codes the dictionary gives the word
+	Got      string   // The generated code, empty on failure
+	Err      error    // Why the word could not be encoded, if it could not
+}
+
+// MismatchRate returns the share of phrases that were not encoded as the
+// dictionary does, failures included.
+func (r LengthReport) MismatchRate() float64 {
+	if r.Phrases == 0 {
+		return 0
+	}
+	return float64(r.Mismatches+r.Failures) / float64(r.Phrases)
+}
+
+// VerifyEncoder encodes every phrase of the view again and compares the
+// result with the codes the view gives it, keeping up to examples
+// mismatches per length. A phrase matches if the generated code is any
+// of its codes; an ambiguous phrase matches if any candidate does.
+func VerifyEncoder(enc Encoder, v *MergedView, examples int) []LengthReport {
+	words, codes := phraseCodes(v)
+
+	reports := make(map[int]*LengthReport)
+	for _, word := range words {
+		n := len([]rune(word))
+		r, ok := reports[n]
+		if !ok {
+			r = &LengthReport{Length: n}
+			reports[n] = r
+		}
+		r.Phrases++
+
+		expected := codes[word]
+		got, err := enc.GenerateCode(word)
+		var ambiguous *AmbiguousCodeError
+		switch {
+		case errors.As(err, &ambiguous):
+			if slices.ContainsFunc(ambiguous.Candidates, func(c string) bool { return slices.Contains(expected, c) }) {
+				continue
+			}
+			r.Mismatches++
+			got = ambiguous.Candidates[0]
+			err = nil
+		case err != nil:
+			r.Failures++
+		case slices.Contains(expected, got):
+			continue
+		default:
+			r.Mismatches++
+		}
+		if len(r.Examples) < examples {
+			r.Examples = append(r.Examples, Mismatch{Word: word, Expected: expected, Got: got, Err: err})
+		}
+	}
+
+	var sorted []LengthReport
+	for _, r := range reports {
+		sorted = append(sorted, *r)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Length < sorted[j].Length })
+	return sorted
+}
+
+// phraseCodes returns the words of at least two characters in the view,
+// in load order, and every code each of them has.
+func phraseCodes(v *MergedView) ([]string, map[string][]string) {
+	var words []string
+	codes := make(map[string][]string)
+	for _, e := range v.Entries {
+		word, code := e.Entry.Word, e.Entry.Code
+		if len([]rune(word)) < 2 || code == "" {
+			continue
+		}
+		if _, ok := codes[word]; !ok {
+			words = append(words, word)
+		}
+		if !slices.Contains(codes[word], code) {
+			codes[word] = append(codes[word], code)
+		}
+	}
+	return words, codes
+}
+
+// SuggestRule finds the formula that best reproduces the codes the view
+// gives the phrases rule applies to. Each letter of the code is matched
+// on its own against every pair of a character (A–T from the start, U–Z
+// from the end) and a letter of its full code. It returns the suggested
+// rule and the share of phrases it encodes correctly; ok is false if the
+// view has no phrases to learn from.
+func (e *TableEncoder) SuggestRule(v *MergedView, rule EncodingRule) (suggested EncodingRule, fit float64, ok bool) {
+	words, codes := phraseCodes(v)
+
+	// Learn from the phrases with an unambiguous full code per character,
+	// using their longest code.
+	type sample struct {
+		runes []rune
+		full  map[int]string
+		code  string
+	}
+	var samples []sample
+	lengths := make(map[int]int)
+	for _, word := range words {
+		runes := []rune(word)
+		if !rule.Matches(len(runes)) {
+			continue
+		}
+		s := sample{runes: runes, full: make(map[int]string)}
+		for i, r := range runes {
+			full := e.fullCodes(r)
+			if len(full) != 1 {
+				s.full = nil
+				break
+			}
+			s.full[i] = full[0]
+		}
+		if s.full == nil {
+			continue
+		}
+		for _, code := range codes[word] {
+			if len(code) > len(s.code) {
+				s.code = code
+			}
+		}
+		samples = append(samples, s)
+		lengths[len(s.code)]++
+	}
+	if len(samples) == 0 {
+		return rule, 0, false
+	}
+
+	// The usual code length for such phrases.
+	codeLength := 0
+	for n, count := range lengths {
+		if count > lengths[codeLength] || count == lengths[codeLength] && n < codeLength {
+			codeLength = n
+		}
+	}
+	samples = slices.DeleteFunc(samples, func(s sample) bool { return len(s.code) != codeLength })
+
+	var charLetters []byte
+	for i := 0; i < min(rule.MinLength, 20); i++ {
+		charLetters = append(charLetters, byte('A'+i))
+	}
+	for c := byte('Z'); c >= 'U'; c-- {
+		charLetters = append(charLetters, c)
+	}
+	codeLetters := []byte("abcdez")
+
+	var formula []byte
+	for p := 0; p < codeLength; p++ {
+		best, bestCount := "", -1
+		for _, c := range charLetters {
+			for _, l := range codeLetters {
+				pair := string([]byte{c, l})
+				coords, _ := EncodingRule{Formula: pair}.coords()
+				count := 0
+				for _, s := range samples {
+					i, ok := resolveIndex(coords[0].char, len(s.runes))
+					if !ok {
+						continue
+					}
+					k, ok := resolveIndex(coords[0].code, len(s.full[i]))
+					if ok && s.full[i][k] == s.code[p] {
+						count++
+					}
+				}
+				if count > bestCount {
+					best, bestCount = pair, count
+				}
+			}
+		}
+		formula = append(formula, best...)
+	}
+
+	suggested = EncodingRule{MinLength: rule.MinLength, MaxLength: rule.MaxLength, Formula: string(formula)}
+	coords, err := suggested.coords()
+	if err != nil {
+		return rule, 0, false
+	}
+	matches := 0
+	for _, s := range samples {
+		if code, _ := applyCoords(coords, s.runes, s.full); code == s.code {
+			matches++
+		}
+	}
+	return suggested, float64(matches) / float64(len(samples)), true
+}
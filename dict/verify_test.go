@@ -0,0 +1,45 @@
+package dict
+
+import (
+	"testing"
+)
+
+func TestVerifyEncoder(t *testing.T) {
+	view, err := LoadMerged("", wubiSampleDict)
+	if err != nil {
+		t.Fatalf("LoadMerged failed: %v", err)
+	}
+
+	reports := VerifyEncoder(NewWubiEncoderFromView(view), view, 3)
+	for _, r := range reports {
+		if r.Mismatches != 0 || r.Failures != 0 {
+			t.Errorf("Length %d: %d mismatches, %d failures, examples %+v", r.Length, r.Mismatches, r.Failures, r.Examples)
+		}
+	}
+	if len(reports) != 4 || reports[0].Length != 2 || reports[0].Phrases != 10 {
+		t.Errorf("Unexpected reports %+v", reports)
+	}
+
+	// A wrong two-character rule shows up in the report and gets fixed
+	// by the suggestion.
+	wrong := EncodingRule{MinLength: 2, MaxLength: 2, Formula: "AaBaAbBb"}
+	encoder := NewTableEncoderFromView(view, append([]EncodingRule{wrong}, Wubi86Rules[1:]...))
+	reports = VerifyEncoder(encoder, view, 3)
+	if reports[0].Mismatches != 10 || len(reports[0].Examples) != 3 || reports[0].MismatchRate() != 1 {
+		t.Errorf("Expected every 2-character phrase to mismatch, got %+v", reports[0])
+	}
+	if reports[1].Mismatches != 0 {
+		t.Errorf("3-character phrases should still match, got %+v", reports[1])
+	}
+	if m := reports[0].Examples[0]; m.Word != "中国" || m.Expected[0] != "khlg" || m.Got != "klhg" {
+		t.Errorf("Unexpected example %+v", m)
+	}
+
+	suggested, fit, ok := encoder.SuggestRule(view, wrong)
+	if !ok || suggested.Formula != "AaAbBaBb" || fit != 1 {
+		t.Errorf("SuggestRule = %v, %v, %v; want 2:AaAbBaBb fitting every phrase", suggested, fit, ok)
+	}
+	if _, _, ok := encoder.SuggestRule(view, EncodingRule{MinLength: 5, MaxLength: 6, Formula: "AaBaCaZa"}); ok {
+		t.Error("SuggestRule should report that there are no 5 or 6 character phrases")
+	}
+}
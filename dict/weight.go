@@ -0,0 +1,97 @@
+package dict
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// weightNumberRe matches the numbers Rime reads in a weight column: plain
+// decimals, without exponents, hex, underscores, Inf or NaN.
+var weightNumberRe = regexp.MustCompile(`^[+-]?[0-9]+(\.[0-9]+)?$`)
+
+// WeightKind tells how the weight column of an entry was written.
+type WeightKind int
+
+const (
+	WeightAbsent   WeightKind = iota // The entry has no weight
+	WeightAbsolute                   // A plain number such as 100
+	WeightPercent                    // A percentage such as 10%
+	WeightInvalid                    // Text that is not a weight, kept as it is
+)
+
+// Weight is the value of an entry's weight column. The zero value is an
+// absent weight.
+type Weight struct {
+	Kind  WeightKind
+	Value float64
+	Raw   string // The text of an invalid weight
+}
+
+// AbsoluteWeight returns a plain numeric weight.
+func AbsoluteWeight(n int) Weight {
+	return Weight{Kind: WeightAbsolute, Value: float64(n)}
+}
+
+// PercentWeight returns a percentage weight, e.g. PercentWeight(10) is 10%.
+func PercentWeight(p float64) Weight {
+	return Weight{Kind: WeightPercent, Value: p}
+}
+
+// ParseWeight parses a weight column: a decimal number such as 100 or
+// -0.5, optionally followed by '%'. An empty string is an absent weight.
+func ParseWeight(s string) (Weight, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Weight{}, nil
+	}
+
+	kind := WeightAbsolute
+	number := s
+	if strings.HasSuffix(s, "%") {
+		kind = WeightPercent
+		number = strings.TrimSpace(strings.TrimSuffix(s, "%"))
+	}
+	if !weightNumberRe.MatchString(number) {
+		return Weight{}, fmt.Errorf("invalid weight '%s': must be a number or a percentage such as 10%%", s)
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil || math.IsInf(value, 0) {
+		return Weight{}, fmt.Errorf("invalid weight '%s': must be a number or a percentage such as 10%%", s)
+	}
+	return Weight{Kind: kind, Value: value}, nil
+}
+
+// InvalidWeight returns the weight of a column that could not be parsed,
+// so that it can be written back unchanged.
+func InvalidWeight(raw string) Weight {
+	return Weight{Kind: WeightInvalid, Raw: raw}
+}
+
+// IsAbsent reports whether the entry has no weight.
+func (w Weight) IsAbsent() bool {
+	return w.Kind == WeightAbsent
+}
+
+// IsNumeric reports whether the weight is an absolute or a percentage
+// weight, whose Value can be compared.
+func (w Weight) IsNumeric() bool {
+	return w.Kind == WeightAbsolute || w.Kind == WeightPercent
+}
+
+// String formats the weight as it is written in a dictionary file. An
+// absent weight formats as an empty string and an invalid one as its
+// original text.
+func (w Weight) String() string {
+	switch w.Kind {
+	case WeightAbsolute:
+		return strconv.FormatFloat(w.Value, 'f', -1, 64)
+	case WeightPercent:
+		return strconv.FormatFloat(w.Value, 'f', -1, 64) + "%"
+	case WeightInvalid:
+		return w.Raw
+	}
+	return ""
+}
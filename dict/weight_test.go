@@ -0,0 +1,100 @@
+package dict
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseWeight(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected Weight
+		text     string
+		hasError bool
+	}{
+		{"", Weight{}, "", false},
+		{"100", AbsoluteWeight(100), "100", false},
+		{" 0 ", AbsoluteWeight(0), "0", false},
+		{"10%", PercentWeight(10), "10%", false},
+		{"0.5%", PercentWeight(0.5), "0.5%", false},
+		{"-3", AbsoluteWeight(-3), "-3", false},
+		{"abc", Weight{}, "", true},
+		{"%", Weight{}, "", true},
+		{"NaN", Weight{}, "", true},
+		{"inf", Weight{}, "", true},
+		{"+Inf", Weight{}, "", true},
+		{"1e3", Weight{}, "", true},
+		{"0x1p4", Weight{}, "", true},
+		{"1_000", Weight{}, "", true},
+		{".5", Weight{}, "", true},
+		{"NaN%", Weight{}, "", true},
+		{"1" + strings.Repeat("0", 400), Weight{}, "", true},
+		{"+2.50", Weight{Kind: WeightAbsolute, Value: 2.5}, "2.5", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			w, err := ParseWeight(tc.input)
+			if (err != nil) != tc.hasError {
+				t.Fatalf("Expected error: %v, got: %v", tc.hasError, err)
+			}
+			if w != tc.expected {
+				t.Errorf("Expected weight %+v, got %+v", tc.expected, w)
+			}
+			if w.String() != tc.text {
+				t.Errorf("Expected text '%s', got '%s'", tc.text, w.String())
+			}
+		})
+	}
+}
+
+func TestDictionary_WeightKinds(t *testing.T) {
+	content := "---\n...\nabsent\ta\npercent\tb\t10%\nabsolute\tc\t5\n"
+	dictPath := createTempDictFile(t, t.TempDir(), content)
+
+	d := NewDictionary(dictPath)
+	if err := d.Load(); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	expected := []Weight{{}, PercentWeight(10), AbsoluteWeight(5)}
+	for i, w := range expected {
+		if d.Entries[i].Weight != w {
+			t.Errorf("Entry %d: expected weight %+v, got %+v", i, w, d.Entries[i].Weight)
+		}
+		// Force every line to be re-rendered.
+		d.Entries[i].Dirty = true
+	}
+
+	if err := d.Save(); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	saved := readFile(t, dictPath)
+	if saved != content {
+		t.Errorf("Weights were not preserved:\ngot:\n%q\nwant:\n%q", saved, content)
+	}
+}
+
+func TestDictionary_LoadInvalidWeight(t *testing.T) {
+	dictPath := createTempDictFile(t, t.TempDir(), "---\n...\nword\tcode\theavy\nother\tcode\t1\n")
+	d := NewDictionary(dictPath)
+	if err := d.Load(); err != nil {
+		t.Fatalf("Load() should keep a non-numeric weight, got: %v", err)
+	}
+	if d.Entries[0].Weight != InvalidWeight("heavy") {
+		t.Errorf("Expected an invalid weight, got %+v", d.Entries[0].Weight)
+	}
+	if len(d.Warnings) != 1 || !strings.Contains(d.Warnings[0], "line 3") {
+		t.Errorf("Expected a warning for line 3, got %q", d.Warnings)
+	}
+
+	// The weight survives a change to another column of the entry.
+	d.AddOrUpdate("other", "code", AbsoluteWeight(2), "")
+	d.Entries[0].Code = "edoc"
+	d.Entries[0].Dirty = true
+	if err := d.Save(); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	if saved := readFile(t, dictPath); saved != "---\n...\nword\tedoc\theavy\nother\tcode\t2\n" {
+		t.Errorf("Unexpected content:\n%q", saved)
+	}
+}
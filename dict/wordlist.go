@@ -0,0 +1,100 @@
+package dict
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WordListRow is a word read from a word list, with the optional columns
+// as written.
+type WordListRow struct {
+	Line   int // Line number in the list, for messages
+	Word   string
+	Code   string
+	Weight string
+	Group  string
+}
+
+// WordListFormats lists the formats ParseWordList accepts. A plain list
+// has one word per line, and TSV and CSV lists have the columns word,
+// code, weight and group, of which all but the word may be left out or
+// empty.
+var WordListFormats = []string{"plain", "tsv", "csv"}
+
+// ParseWordList reads a word list. Blank lines and lines starting with
+// '#' are ignored, as is a first row naming the columns, e.g.
+// "word,code,weight,group".
+func ParseWordList(r io.Reader, format string) ([]WordListRow, error) {
+	var records [][]string
+	var lines []int
+	switch format {
+	case "plain", "tsv":
+		reader := newLineReader(r)
+		for n := 1; ; n++ {
+			line, _, err := reader.next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("error reading word list: %w", err)
+			}
+			if format == "plain" {
+				records = append(records, []string{line})
+			} else {
+				records = append(records, strings.Split(line, "\t"))
+			}
+			lines = append(lines, n)
+		}
+	case "csv":
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		reader.Comment = '#'
+		reader.TrimLeadingSpace = true
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("error reading word list: %w", err)
+			}
+			line, _ := reader.FieldPos(0)
+			records = append(records, record)
+			lines = append(lines, line)
+		}
+	default:
+		return nil, fmt.Errorf("invalid format '%s': must be one of %s", format, strings.Join(WordListFormats, ", "))
+	}
+
+	var rows []WordListRow
+	for i, record := range records {
+		for j := range record {
+			record[j] = strings.TrimSpace(record[j])
+		}
+		record[0] = strings.TrimPrefix(record[0], utf8BOM)
+		if record[0] == "" || strings.HasPrefix(record[0], "#") {
+			continue
+		}
+		if len(rows) == 0 && isColumnNames(record) {
+			continue
+		}
+		if len(record) > 4 {
+			return nil, fmt.Errorf("line %d: expected at most 4 columns (word, code, weight, group), got %d", lines[i], len(record))
+		}
+		record = append(record, make([]string, 4-len(record))...)
+		rows = append(rows, WordListRow{Line: lines[i], Word: record[0], Code: record[1], Weight: record[2], Group: record[3]})
+	}
+	return rows, nil
+}
+
+// isColumnNames reports whether a record names the columns rather than
+// holding a word.
+func isColumnNames(record []string) bool {
+	switch strings.ToLower(record[0]) {
+	case "word", "text", "词语":
+		return true
+	}
+	return false
+}
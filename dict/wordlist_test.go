@@ -0,0 +1,63 @@
+package dict
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseWordList(t *testing.T) {
+	testCases := []struct {
+		name     string
+		format   string
+		input    string
+		expected []WordListRow
+	}{
+		{
+			name:   "plain",
+			format: "plain",
+			input:  "# my words\n测试\n\n 工作 \n",
+			expected: []WordListRow{
+				{Line: 2, Word: "测试"},
+				{Line: 4, Word: "工作"},
+			},
+		},
+		{
+			name:   "tsv with column names",
+			format: "tsv",
+			input:  "word\tcode\tweight\tgroup\r\n测试\r\n工作\taawt\t20\r\n中国\t\t\t新组\r\n",
+			expected: []WordListRow{
+				{Line: 2, Word: "测试"},
+				{Line: 3, Word: "工作", Code: "aawt", Weight: "20"},
+				{Line: 4, Word: "中国", Group: "新组"},
+			},
+		},
+		{
+			name:   "csv",
+			format: "csv",
+			input:  "\xef\xbb\xbfword,code,weight\n测试,imya\n\"工,作\", aawt,10%\n",
+			expected: []WordListRow{
+				{Line: 2, Word: "测试", Code: "imya"},
+				{Line: 3, Word: "工,作", Code: "aawt", Weight: "10%"},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rows, err := ParseWordList(strings.NewReader(tc.input), tc.format)
+			if err != nil {
+				t.Fatalf("ParseWordList failed: %v", err)
+			}
+			if !reflect.DeepEqual(rows, tc.expected) {
+				t.Errorf("ParseWordList = %+v, want %+v", rows, tc.expected)
+			}
+		})
+	}
+
+	if _, err := ParseWordList(strings.NewReader("a\tb\tc\td\te\n"), "tsv"); err == nil {
+		t.Error("ParseWordList should reject rows with too many columns")
+	}
+	if _, err := ParseWordList(strings.NewReader("a\n"), "xlsx"); err == nil {
+		t.Error("ParseWordList should reject an unknown format")
+	}
+}
@@ -0,0 +1,33 @@
+package dict
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// WubiEncoder is a TableEncoder using Wubi86Rules.
+type WubiEncoder = TableEncoder
+
+// NewWubiEncoder creates an encoder by loading a main dictionary file.
+func NewWubiEncoder(mainDictPath string) (*WubiEncoder, error) {
+	file, err := os.Open(mainDictPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open main dictionary '%s': %w", mainDictPath, err)
+	}
+	defer file.Close()
+
+	return NewWubiEncoderFromReader(file)
+}
+
+// NewWubiEncoderFromReader creates an encoder from a main dictionary read
+// from r.
+func NewWubiEncoderFromReader(r io.Reader) (*WubiEncoder, error) {
+	return NewTableEncoderFromReader(r, Wubi86Rules)
+}
+
+// NewWubiEncoderFromView creates an encoder from the single characters of
+// a merged dictionary view.
+func NewWubiEncoderFromView(v *MergedView) *WubiEncoder {
+	return NewTableEncoderFromView(v, Wubi86Rules)
+}
@@ -0,0 +1,150 @@
+package dict
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+const wubiSampleDict = "testdata/wubi86_jidian_sample.dict.yaml"
+
+func TestWubiEncoder_PhraseRules(t *testing.T) {
+	encoder, err := NewWubiEncoder(wubiSampleDict)
+	if err != nil {
+		t.Fatalf("NewWubiEncoder failed: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		word     string
+		expected string
+	}{
+		{"one char", "国", "lgyi"},
+		{"two chars", "工作", "aawt"},
+		{"two chars with short char code", "汉字", "icpb"},
+		{"two chars with key-name char", "中国", "khlg"},
+		{"three chars", "计算机", "ytsm"},
+		{"three chars ending in key-name char", "中国人", "klww"},
+		{"four chars", "中国人民", "klwn"},
+		{"seven chars", "中华人民共和国", "kwwl"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			code, err := encoder.GenerateCode(tc.word)
+			if err != nil {
+				t.Fatalf("GenerateCode(%s) failed: %v", tc.word, err)
+			}
+			if code != tc.expected {
+				t.Errorf("GenerateCode(%s) = %s, want %s", tc.word, code, tc.expected)
+			}
+		})
+	}
+}
+
+// TestWubiEncoder_SampleDictPhrases re-encodes every phrase of the sample
+// dictionary and compares it with the code the dictionary gives it.
+func TestWubiEncoder_SampleDictPhrases(t *testing.T) {
+	d := NewDictionary(wubiSampleDict)
+	if err := d.Load(); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	encoder, err := NewWubiEncoder(wubiSampleDict)
+	if err != nil {
+		t.Fatalf("NewWubiEncoder failed: %v", err)
+	}
+
+	phrases := 0
+	for _, entry := range d.Entries {
+		if utf8.RuneCountInString(entry.Word) < 2 {
+			continue
+		}
+		phrases++
+		t.Run(entry.Word, func(t *testing.T) {
+			code, err := encoder.GenerateCode(entry.Word)
+			if err != nil {
+				t.Fatalf("GenerateCode(%s) failed: %v", entry.Word, err)
+			}
+			if code != entry.Code {
+				t.Errorf("GenerateCode(%s) = %s, the dictionary says %s", entry.Word, code, entry.Code)
+			}
+		})
+	}
+	if phrases == 0 {
+		t.Fatalf("No phrases found in %s", filepath.Base(wubiSampleDict))
+	}
+}
+
+func TestWubiEncoder_OnlyNeededCharacters(t *testing.T) {
+	encoder, err := NewWubiEncoder(wubiSampleDict)
+	if err != nil {
+		t.Fatalf("NewWubiEncoder failed: %v", err)
+	}
+
+	// 囗 is not in the dictionary, but a 4+ char word only needs its
+	// first three and last characters.
+	if code, err := encoder.GenerateCode("中国人囗民"); err != nil || code != "klwn" {
+		t.Errorf("Expected klwn, got %s (err: %v)", code, err)
+	}
+	if _, err := encoder.GenerateCode("中国囗"); err == nil {
+		t.Error("Expected an error for a missing character")
+	}
+}
+
+func TestWubiEncoder_PrefersFullCode(t *testing.T) {
+	// The simple code comes last, so it used to overwrite the full code.
+	encoder, err := NewWubiEncoderFromReader(strings.NewReader("中\tkhk\n中\tk\n国\tl\n国\tlgyi\n"))
+	if err != nil {
+		t.Fatalf("NewWubiEncoderFromReader failed: %v", err)
+	}
+
+	for word, expected := range map[string]string{"中": "khk", "国": "lgyi", "中国": "khlg"} {
+		if code, err := encoder.GenerateCode(word); err != nil || code != expected {
+			t.Errorf("GenerateCode(%s) = %s (err: %v), want %s", word, code, err, expected)
+		}
+	}
+}
+
+func TestWubiEncoder_Ambiguous(t *testing.T) {
+	// 甲 has two full codes that differ in the first two letters, 乙 in the
+	// fourth letter only.
+	content := "甲\tabcd\n甲\tefgh\n乙\tnnnl\n乙\tnnnm\n丙\tgmwi\n"
+	encoder, err := NewWubiEncoderFromReader(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("NewWubiEncoderFromReader failed: %v", err)
+	}
+
+	testCases := []struct {
+		word       string
+		expected   string
+		candidates []string
+	}{
+		{"甲丙", "", []string{"abgm", "efgm"}},
+		{"乙丙", "nngm", nil},
+		{"丙乙丙丙", "gngg", nil},
+		{"乙", "", []string{"nnnl", "nnnm"}},
+		{"丙甲甲", "", []string{"gaab", "gaef", "geab", "geef"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.word, func(t *testing.T) {
+			code, err := encoder.GenerateCode(tc.word)
+			var ambiguous *AmbiguousCodeError
+			if tc.candidates == nil {
+				if err != nil || code != tc.expected {
+					t.Errorf("GenerateCode(%s) = %s (err: %v), want %s", tc.word, code, err, tc.expected)
+				}
+				return
+			}
+			if !errors.As(err, &ambiguous) {
+				t.Fatalf("Expected an AmbiguousCodeError, got code %s, err %v", code, err)
+			}
+			if !reflect.DeepEqual(ambiguous.Candidates, tc.candidates) {
+				t.Errorf("Candidates = %v, want %v", ambiguous.Candidates, tc.candidates)
+			}
+		})
+	}
+}